@@ -0,0 +1,223 @@
+package seatbelt
+
+import (
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// sourceTags are the struct tags that bind a field to a single source of
+// params, in order of precedence, ie,
+//
+//	type Search struct {
+//		Page   int    `query:"page"`
+//		Tenant string `header:"X-Tenant"`
+//		UserID int    `path:"id"`
+//	}
+//
+// A field with a source tag is only ever assigned from that source, by
+// Params and Bind as well as the source's own Bind method.
+var sourceTags = []string{"path", "header", "query"}
+
+// hasSourceTag returns true if the given field has one of the sourceTags.
+func hasSourceTag(sf reflect.StructField) bool {
+	for _, tag := range sourceTags {
+		if sf.Tag.Get(tag) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// bindSources replaces the params for each field of the given struct type
+// that has a source tag with the value from the first of the given sources
+// that the field is tagged with. If none of them have a value, the field
+// isn't assigned.
+func (c *context) bindSources(values map[string]interface{}, t reflect.Type, sources ...string) {
+	if t == nil {
+		return
+	}
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || !hasSourceTag(sf) {
+			continue
+		}
+
+		key := paramsFieldName(sf)
+		for k := range values {
+			if strings.EqualFold(k, key) || strings.EqualFold(k, sf.Name) {
+				delete(values, k)
+			}
+		}
+
+		for _, source := range sources {
+			name := sf.Tag.Get(source)
+			if name == "" {
+				continue
+			}
+			if value, ok := c.sourceValue(source, name); ok {
+				values[key] = value
+				break
+			}
+		}
+	}
+}
+
+// sourceValue returns the value of the param with the given name from the
+// given source, and true if it's present.
+func (c *context) sourceValue(source, name string) (interface{}, bool) {
+	var values []string
+
+	switch source {
+	case "path":
+		if rctx := chi.RouteContext(c.r.Context()); rctx != nil {
+			for i, key := range rctx.URLParams.Keys {
+				if key == name {
+					values = []string{rctx.URLParams.Values[i]}
+				}
+			}
+		}
+	case "header":
+		values = c.r.Header.Values(name)
+	case "query":
+		values = c.r.URL.Query()[name]
+	}
+
+	switch len(values) {
+	case 0:
+		return nil, false
+	case 1:
+		return values[0], true
+	}
+	return values, true
+}
+
+// BindQuery assigns the query params to the given struct, as Params does,
+// but ignores the path params and the request body, and then validates it,
+// as Bind does. This is useful for search filters, ie,
+//
+//	var filter struct {
+//		Q    string
+//		Page int `query:"page"`
+//	}
+//	if err := c.BindQuery(&filter); err != nil {
+//		return err
+//	}
+func (c *context) BindQuery(v interface{}, opts ...ParamsOption) error {
+	values := make(map[string]interface{})
+	for key, val := range c.r.URL.Query() {
+		setParam(values, key, val)
+	}
+
+	if err := c.decodeFrom(values, v, opts, "query"); err != nil {
+		return err
+	}
+	return c.validate(v)
+}
+
+// BindForm assigns the form params in the request body, including uploaded
+// files, to the given struct, as Params does, but ignores the query and
+// path params, and then validates it, as Bind does.
+func (c *context) BindForm(v interface{}, opts ...ParamsOption) error {
+	if err := c.parseForm(); err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	for key, val := range c.r.PostForm {
+		setParam(values, key, val)
+	}
+	if c.r.MultipartForm != nil {
+		for key, files := range c.r.MultipartForm.File {
+			setFiles(values, key, files)
+		}
+	}
+
+	if err := c.decodeFrom(values, v, opts); err != nil {
+		return err
+	}
+	return c.validate(v)
+}
+
+// BindPath assigns the path params to the given struct, and then validates
+// it, as Bind does.
+func (c *context) BindPath(v interface{}) error {
+	values := c.pathParams()
+	c.bindSources(values, reflect.TypeOf(v), "path")
+	if err := c.decode(values, v); err != nil {
+		return err
+	}
+	return c.validate(v)
+}
+
+// BindHeader assigns the request headers to the fields of the given struct
+// that have a header tag, and then validates it, as Bind does. Fields
+// without a header tag are never assigned, since any header can be sent by
+// the client.
+func (c *context) BindHeader(v interface{}) error {
+	values := make(map[string]interface{})
+	c.bindSources(values, reflect.TypeOf(v), "header")
+	if err := c.decode(values, v); err != nil {
+		return err
+	}
+	return c.validate(v)
+}
+
+// BindJSON assigns the JSON request body to the given struct, as Params
+// does, but ignores the query and path params, and then validates it, as
+// Bind does. If the request body isn't JSON, an HTTPError with the status
+// 415 Unsupported Media Type is returned.
+func (c *context) BindJSON(v interface{}, opts ...ParamsOption) error {
+	mediaType, _, _ := mime.ParseMediaType(c.r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "the request body must be JSON")
+	}
+
+	values := make(map[string]interface{})
+	data, err := c.readJSONParams(values, v, newPermitTree(opts))
+	if err != nil {
+		return err
+	}
+
+	if isStructPtr(v) {
+		err = decodeJSONStruct(data, v, c.disallowUnknownFields())
+	} else {
+		err = c.decode(values, v)
+	}
+	if err != nil {
+		return err
+	}
+	return c.validate(v)
+}
+
+// decodeFrom decodes the given params from a single source into v, after
+// removing the params that the given options don't permit, and assigning
+// the fields tagged with the given sources.
+func (c *context) decodeFrom(values map[string]interface{}, v interface{}, opts []ParamsOption, sources ...string) error {
+	collapseIndexes(values)
+
+	if err := c.unpermitted(filterParams(values, reflect.TypeOf(v), newPermitTree(opts), paramsFieldName, "")); err != nil {
+		return err
+	}
+	c.bindSources(values, reflect.TypeOf(v), sources...)
+
+	return c.decode(values, v)
+}
+
+// validate validates the given struct, including any validators registered
+// with the application.
+func (c *context) validate(v interface{}) error {
+	var custom map[string]ValidatorFunc
+	if c.app != nil {
+		custom = c.app.validators
+	}
+	return validate(v, custom)
+}
@@ -0,0 +1,128 @@
+package seatbelt_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+type search struct {
+	Q      string
+	Sort   string
+	Page   int    `query:"page"`
+	Tenant string `header:"X-Tenant"`
+	ID     int    `path:"id"`
+}
+
+func TestContextBindSources(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		bind        func(c seatbelt.Context, v interface{}) error
+		expected    search
+	}{
+		{
+			name:        "Params",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "q=body&page=9&tenant=evil&id=9",
+			bind:        func(c seatbelt.Context, v interface{}) error { return c.Params(v) },
+			expected:    search{Q: "body", Sort: "name", Page: 2, Tenant: "acme", ID: 5},
+		},
+		{
+			name:        "Params with JSON",
+			contentType: "application/json",
+			body:        `{"q": "body", "page": 9, "tenant": "evil", "id": 9}`,
+			bind:        func(c seatbelt.Context, v interface{}) error { return c.Params(v) },
+			expected:    search{Q: "body", Sort: "name", Page: 2, Tenant: "acme", ID: 5},
+		},
+		{
+			name:        "BindQuery",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "q=body&sort=date",
+			bind:        func(c seatbelt.Context, v interface{}) error { return c.BindQuery(v) },
+			expected:    search{Q: "query", Sort: "name", Page: 2},
+		},
+		{
+			name:        "BindForm",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "q=body&page=9",
+			bind:        func(c seatbelt.Context, v interface{}) error { return c.BindForm(v) },
+			expected:    search{Q: "body"},
+		},
+		{
+			name:        "BindPath",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "q=body",
+			bind:        func(c seatbelt.Context, v interface{}) error { return c.BindPath(v) },
+			expected:    search{ID: 5},
+		},
+		{
+			name:        "BindHeader",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "q=body",
+			bind:        func(c seatbelt.Context, v interface{}) error { return c.BindHeader(v) },
+			expected:    search{Tenant: "acme"},
+		},
+		{
+			name:        "BindJSON",
+			contentType: "application/json",
+			body:        `{"q": "body", "sort": "date", "page": 9}`,
+			bind:        func(c seatbelt.Context, v interface{}) error { return c.BindJSON(v) },
+			expected:    search{Q: "body", Sort: "date"},
+		},
+	}
+
+	for _, tc := range cases {
+		var v search
+		app := seatbelt.New()
+		app.Post("/tenants/{id}/search", func(c seatbelt.Context) error {
+			if err := tc.bind(c, &v); err != nil {
+				return err
+			}
+			return c.NoContent()
+		})
+
+		r := httptest.NewRequest(http.MethodPost, "/tenants/5/search?q=query&sort=name&page=2", strings.NewReader(tc.body))
+		r.Header.Set("Content-Type", tc.contentType)
+		r.Header.Set("X-Tenant", "acme")
+		r.Header.Set("Q", "header")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("%s: expected HTTP 204 but got %d: %s", tc.name, w.Code, w.Body.String())
+		}
+		if v != tc.expected {
+			t.Fatalf("%s: expected %+v but got %+v", tc.name, tc.expected, v)
+		}
+	}
+}
+
+func TestContextBindJSON(t *testing.T) {
+	var v struct {
+		Name string `validate:"required"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=Jane"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+	err := c.BindJSON(&v)
+	herr, ok := err.(*seatbelt.HTTPError)
+	if !ok || herr.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected HTTP 415 error but got %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": ""}`))
+	r.Header.Set("Content-Type", "application/json")
+	c = seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+	verrs, ok := c.BindJSON(&v).(seatbelt.ValidationErrors)
+	if !ok || !verrs.Has("name") {
+		t.Fatalf("expected a validation error for name but got %v", verrs)
+	}
+}
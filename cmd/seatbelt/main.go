@@ -0,0 +1,88 @@
+// Command seatbelt provides tools for working with Seatbelt applications.
+//
+// Usage:
+//
+//	seatbelt check [-dir views] [-data fixtures.json] [-layout application]
+//
+// The check command parses every template in the template directory, reports
+// references to templates that don't exist, and executes each template with
+// its sample data from the given JSON file, which maps template names to
+// their data, reporting any key that the data doesn't have. It exits with a
+// non-zero status if any problems are found.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: seatbelt check [-dir views] [-data fixtures.json] [-layout application]")
+	os.Exit(2)
+}
+
+func check(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	dir := fs.String("dir", "views", "the directory where the templates reside")
+	dataPath := fs.String("data", "", "a JSON file mapping template names to sample data")
+	layout := fs.String("layout", "application", "the layout to execute templates against")
+	fs.Parse(args)
+
+	opt := seatbelt.CheckOption{
+		Layout: *layout,
+
+		// The application's funcs aren't available from the command line.
+		IgnoreUnknownFuncs: true,
+	}
+
+	if *dataPath != "" {
+		buf, err := ioutil.ReadFile(*dataPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "seatbelt: %v\n", err)
+			return 1
+		}
+		if err := json.Unmarshal(buf, &opt.Data); err != nil {
+			fmt.Fprintf(os.Stderr, "seatbelt: %s is not valid JSON: %v\n", *dataPath, err)
+			return 1
+		}
+	}
+
+	err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: *dir}, opt)
+	if err == nil {
+		return 0
+	}
+
+	var terrs seatbelt.TemplateErrors
+	if errors.As(err, &terrs) {
+		for _, terr := range terrs {
+			// Report paths relative to the working directory, rather than
+			// the template directory, so that editors can jump to them.
+			e := *terr
+			e.File = filepath.Join(*dir, e.File)
+			fmt.Fprintln(os.Stderr, e.Error())
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "seatbelt: %v\n", err)
+	}
+	return 1
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "check":
+		os.Exit(check(os.Args[2:]))
+	default:
+		usage()
+	}
+}
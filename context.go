@@ -3,8 +3,11 @@ package seatbelt
 import (
 	sctx "context"
 	"html/template"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/gorilla/sessions"
@@ -12,53 +15,254 @@ import (
 
 // Context contains values present during the lifetime of an HTTP
 // request/response cycle.
+//
+// A Context is also a context.Context, which is cancelled when the client
+// disconnects or the request times out, so it can be passed directly to
+// anything that accepts one.
 type Context interface {
+	sctx.Context
+
 	// Request returns the *http.Request for the current Context.
 	Request() *http.Request
 
-	// Response returns the http.ResponseWriter for the current Context.
-	Response() http.ResponseWriter
+	// Response returns the ResponseWriter for the current Context.
+	Response() ResponseWriter
 
 	// Session returns the session object for the current context.
 	Session() Session
 
+	// Set saves a value on the context with the given key. Values set on
+	// the context are merged into the data of every template it renders.
+	Set(key string, value interface{})
+
+	// Get returns the value saved on the context with the given key, or nil
+	// if there isn't one.
+	Get(key string) interface{}
+
+	// MustGet returns the value saved on the context with the given key. It
+	// panics if there isn't one.
+	MustGet(key string) interface{}
+
+	// WithRequest returns a copy of the context with its request replaced by
+	// the given request.
+	WithRequest(r *http.Request) Context
+
 	// Params mass-assigns query, path, and form parameters to the given struct or
-	// map.
-	Params(v interface{}) error
+	// map. Only the params permitted by the given options are assigned.
+	Params(v interface{}, opts ...ParamsOption) error
+
+	// Bind mass-assigns query, path, and form parameters to the given
+	// struct, and validates it using the validate tag of each field.
+	Bind(v interface{}, opts ...ParamsOption) error
+
+	// BindQuery assigns only the query params to the given struct, and
+	// validates it.
+	BindQuery(v interface{}, opts ...ParamsOption) error
+
+	// BindForm assigns only the form params in the request body to the given
+	// struct, and validates it.
+	BindForm(v interface{}, opts ...ParamsOption) error
+
+	// BindPath assigns only the path params to the given struct, and
+	// validates it.
+	BindPath(v interface{}) error
+
+	// BindJSON assigns only the JSON request body to the given struct, and
+	// validates it.
+	BindJSON(v interface{}, opts ...ParamsOption) error
+
+	// BindHeader assigns the request headers named by the header tag of each
+	// field to the given struct, and validates it.
+	BindHeader(v interface{}) error
 
 	// FormValue returns the form value with the given name.
 	FormValue(name string) string
 
+	// FormFile returns the first file uploaded with the given field name.
+	FormFile(name string) (*multipart.FileHeader, error)
+
 	// PathParam returns the path parameter with the given name.
 	PathParam(name string) string
 
 	// QueryParam returns the URL query parameter with the given name.
 	QueryParam(name string) string
 
+	// PathParamInt returns the path parameter with the given name as an int,
+	// or an HTTPError with the status 404 Not Found if it isn't one.
+	PathParamInt(name string) (int, error)
+
+	// QueryParamDefault returns the URL query parameter with the given name,
+	// or the given default if it's empty.
+	QueryParamDefault(name, def string) string
+
+	// QueryParamInt returns the URL query parameter with the given name as
+	// an int, or the given default if it's empty.
+	QueryParamInt(name string, def int) (int, error)
+
+	// QueryParamBool returns the URL query parameter with the given name as
+	// a bool, or the given default if it's empty.
+	QueryParamBool(name string, def bool) (bool, error)
+
+	// Paginate returns the Pagination for the current request, from its
+	// page, per_page, and cursor query params.
+	Paginate(opts ...PaginationOption) (*Pagination, error)
+
+	// Negotiate calls the func for the format that best matches what the
+	// client accepts, and returns its error.
+	Negotiate(offers map[string]func() error) error
+
+	// Accepts returns the format, out of the given formats, that best
+	// matches what the client accepts, or an empty string if none of them
+	// do.
+	Accepts(formats ...string) string
+
 	// String sends a string response with the given status code.
 	String(code int, s string) error
 
 	// JSON sends a JSON response with the given status code.
 	JSON(code int, v interface{}) error
 
+	// XML sends an XML response with the given status code.
+	XML(code int, v interface{}) error
+
+	// Blob sends a response with the given status code, content type, and
+	// body.
+	Blob(code int, contentType string, b []byte) error
+
+	// Stream sends a response with the given status code and content type,
+	// copying the body from the given reader.
+	Stream(code int, contentType string, r io.Reader) error
+
+	// File sends the contents of the file at the given path, supporting
+	// range and conditional requests.
+	File(path string) error
+
+	// Attachment sends the file at the given path as a download with the
+	// given filename.
+	Attachment(path, filename string) error
+
+	// Inline sends the file at the given path to be displayed by the
+	// browser, with the given filename.
+	Inline(path, filename string) error
+
 	// Render renders an HTML template.
 	Render(name string, data interface{}, opts ...RenderOption) error
 
-	// NoContent sends a 204 No Content HTTP response. The returned error will
-	// always be nil.
+	// RenderForm re-renders a form template with the given validation
+	// errors, and the values that were submitted with the form.
+	RenderForm(name string, data interface{}, err error, opts ...RenderOption) error
+
+	// RenderToString renders an HTML template to a string.
+	RenderToString(name string, data interface{}, opts ...RenderOption) (string, error)
+
+	// IsHTMX returns true if the request was made by htmx.
+	IsHTMX() bool
+
+	// HXTarget returns the ID of the element htmx will swap the response
+	// into, if there is one.
+	HXTarget() string
+
+	// TurboFrame returns the ID of the Turbo Frame that made the request, if
+	// one did.
+	TurboFrame() string
+
+	// HXRedirect tells htmx to perform a full page redirect to the given url.
+	// The returned error will always be nil.
+	HXRedirect(url string) error
+
+	// HXRefresh tells htmx to perform a full page refresh. The returned error
+	// will always be nil.
+	HXRefresh() error
+
+	// HXTrigger tells htmx to trigger the given client side events once the
+	// response has been received.
+	HXTrigger(events ...string)
+
+	// TurboStream sends the given Turbo Stream actions with the given status
+	// code.
+	TurboStream(code int, streams ...TurboStream) error
+
+	// Fresh sets the ETag and Last-Modified headers, and returns true,
+	// after sending a 304 Not Modified, if the client's cached copy is
+	// still fresh.
+	Fresh(etag string, modTime time.Time) bool
+
+	// SSE starts a stream of Server-Sent Events.
+	SSE() (*EventStream, error)
+
+	// NoContent sends a 204 No Content HTTP response. It returns an error if
+	// a response has already been written.
 	NoContent() error
 
-	// Redirect redirects the to the given url. The returned error will always
-	// be nil.
+	// Redirect redirects the to the given url. It returns an error if a
+	// response has already been written.
 	Redirect(url string) error
 }
 
 // context implements the Context interface.
 type context struct {
-	w      http.ResponseWriter
+	w      ResponseWriter
 	r      *http.Request
 	store  sessions.Store
 	render *Renderer
+
+	// app is the application that's serving the request. It is nil for
+	// test contexts.
+	app *App
+
+	// values are the values set on the context with Set, which are attached
+	// to the request's context.Context.
+	values *contextValues
+
+	// stream is true if the route streams its response, so it must never
+	// be buffered or timed out.
+	stream bool
+
+	// forms are the multipart forms parsed while serving the request, whose
+	// temporary files are removed once it's served. It is nil for test
+	// contexts.
+	forms *multipartForms
+
+	// testSession, if set, is used instead of the cookie session store.
+	testSession Session
+
+	// flashMap holds the flash messages once they've been read from the
+	// session.
+	flashMap    map[string]interface{}
+	flashesRead bool
+
+	// formErrors and formInput are the validation errors and submitted
+	// values of the form being rendered with RenderForm.
+	formErrors ValidationErrors
+	formInput  map[string][]string
+}
+
+// base returns the underlying context.
+func (c *context) base() *context {
+	return c
+}
+
+// Deadline returns the deadline of the request's context, if it has one.
+func (c *context) Deadline() (time.Time, bool) {
+	return c.r.Context().Deadline()
+}
+
+// Done returns a channel that's closed when the request's context is
+// cancelled, ie, when the client disconnects or the request times out.
+func (c *context) Done() <-chan struct{} {
+	return c.r.Context().Done()
+}
+
+// Err returns the reason the request's context was cancelled, if it has
+// been.
+func (c *context) Err() error {
+	return c.r.Context().Err()
+}
+
+// Value returns the value associated with the given key in the request's
+// context.
+func (c *context) Value(key interface{}) interface{} {
+	return c.r.Context().Value(key)
 }
 
 // A TestContext is used for unit testing Seatbelt handlers.
@@ -94,17 +298,21 @@ func NewTestContext(w http.ResponseWriter, r *http.Request, params ...map[string
 	newCtx := sctx.WithValue(r.Context(), chi.RouteCtxKey, rctx)
 
 	// Force the route context onto the request context.
-	r = r.Clone(newCtx)
+	r, values := withValues(r.Clone(newCtx))
+
+	session := &testsession{
+		kv: make(map[string]interface{}),
+	}
 
 	tc := &TestContext{
 		context: &context{
-			w: w,
-			r: r,
-		},
-		Req: r,
-		session: &testsession{
-			kv: make(map[string]interface{}),
+			w:           newResponseWriter(w),
+			r:           r,
+			values:      values,
+			testSession: session,
 		},
+		Req:     r,
+		session: session,
 	}
 
 	if rr, ok := w.(*httptest.ResponseRecorder); ok {
@@ -0,0 +1,121 @@
+package seatbelt
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fresh sets the ETag and Last-Modified headers from the given entity tag
+// and modification time, either of which may be empty, and returns true if
+// the client's cached copy is still fresh. When it is, a 304 Not Modified
+// has already been sent, so the handler should return without doing any
+// more work, ie,
+//
+//	if c.Fresh(post.Version, post.UpdatedAt) {
+//		return nil
+//	}
+//	return c.Render("posts/show", post)
+//
+// An entity tag that isn't quoted is quoted, and sent as a strong entity
+// tag.
+func (c *context) Fresh(etag string, modTime time.Time) bool {
+	h := c.w.Header()
+	if etag != "" {
+		if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = `"` + etag + `"`
+		}
+		h.Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		h.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+
+	if c.w.Committed() || !isFresh(c.r, h) {
+		return false
+	}
+
+	notModified(c.w)
+	return true
+}
+
+// etagEnabled returns true if the application sends an ETag with every
+// buffered response, which is configured on its renderer, so that HTML and
+// JSON responses always agree.
+func (c *context) etagEnabled() bool {
+	return c.render != nil && c.render.etag
+}
+
+// weakETag returns a weak entity tag for the given response body.
+func weakETag(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`W/"%x-%x"`, len(body), h.Sum64())
+}
+
+// setETag sets a weak entity tag for the given response body, unless the
+// handler has already set its own, and returns true if the client's cached
+// copy is still fresh, in which case a 304 Not Modified has been sent.
+func setETag(w http.ResponseWriter, r *http.Request, body []byte) bool {
+	h := w.Header()
+	if h.Get("ETag") == "" {
+		h.Set("ETag", weakETag(body))
+	}
+
+	if !isFresh(r, h) {
+		return false
+	}
+
+	notModified(w)
+	return true
+}
+
+// isFresh returns true if the conditional headers of the given GET or HEAD
+// request match the given response headers.
+//
+// As described in RFC 7232 section 6, If-Modified-Since is ignored when the
+// request has an If-None-Match header.
+func isFresh(r *http.Request, h http.Header) bool {
+	if r == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := h.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims, lm := r.Header.Get("If-Modified-Since"), h.Get("Last-Modified")
+	if ims == "" || lm == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lm)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// notModified sends a 304 Not Modified, without the headers that describe
+// a body.
+func notModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,156 @@
+package seatbelt_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+// conditionalGet requests the given path, first without any conditional
+// headers, and then with If-None-Match set to the returned ETag, and returns
+// both responses.
+func conditionalGet(t *testing.T, url string) (*http.Response, *http.Response) {
+	t.Helper()
+
+	first, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	first.Body.Close()
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatalf("%+v creating request", err)
+	}
+	req.Header.Set("If-None-Match", first.Header.Get("ETag"))
+
+	second, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	second.Body.Close()
+
+	return first, second
+}
+
+func TestETag(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New(seatbelt.Option{
+		TemplateDir: "testdata",
+		ETag:        true,
+		Funcs: template.FuncMap{
+			"lower": strings.ToLower,
+		},
+	})
+	app.Get("/", func(c seatbelt.Context) error {
+		return c.Render("home/index", nil)
+	})
+	app.Get("/json", func(c seatbelt.Context) error {
+		return c.JSON(200, map[string]string{"name": "seatbelt"})
+	})
+	app.Get("/created", func(c seatbelt.Context) error {
+		return c.JSON(201, map[string]string{"name": "seatbelt"})
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	for _, path := range []string{"/", "/json"} {
+		first, second := conditionalGet(t, srv.URL+path)
+
+		etag := first.Header.Get("ETag")
+		if first.StatusCode != 200 || !strings.HasPrefix(etag, `W/"`) {
+			t.Fatalf("expected HTTP 200 with a weak ETag for %s but got %d with %q", path, first.StatusCode, etag)
+		}
+		if second.StatusCode != 304 {
+			t.Fatalf("expected HTTP 304 for %s but got %d", path, second.StatusCode)
+		}
+		if ct := second.Header.Get("Content-Type"); ct != "" {
+			t.Fatalf("expected no content type for a 304 but got %s", ct)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/created")
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	resp.Body.Close()
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		t.Fatalf("expected no ETag for a non 200 response but got %s", etag)
+	}
+}
+
+func TestETagDisabled(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New()
+	app.Get("/json", func(c seatbelt.Context) error {
+		return c.JSON(200, "ok")
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/json")
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	resp.Body.Close()
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		t.Fatalf("expected no ETag but got %s", etag)
+	}
+}
+
+func TestContextFresh(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		header http.Header
+		fresh  bool
+	}{
+		{name: "no conditional headers", header: http.Header{}, fresh: false},
+		{name: "matching etag", header: http.Header{"If-None-Match": {`"v1"`}}, fresh: true},
+		{name: "weak matching etag", header: http.Header{"If-None-Match": {`"v0", W/"v1"`}}, fresh: true},
+		{name: "stale etag", header: http.Header{"If-None-Match": {`"v0"`}}, fresh: false},
+		{name: "not modified since", header: http.Header{"If-Modified-Since": {modTime.Format(http.TimeFormat)}}, fresh: true},
+		{name: "modified since", header: http.Header{"If-Modified-Since": {modTime.Add(-time.Hour).Format(http.TimeFormat)}}, fresh: false},
+		{name: "etag takes precedence", header: http.Header{
+			"If-None-Match":     {`"v0"`},
+			"If-Modified-Since": {modTime.Format(http.TimeFormat)},
+		}, fresh: false},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header = tc.header
+
+		c := seatbelt.NewTestContext(httptest.NewRecorder(), r, nil)
+
+		if fresh := c.Fresh("v1", modTime); fresh != tc.fresh {
+			t.Fatalf("%s: expected fresh to be %t but got %t", tc.name, tc.fresh, fresh)
+		}
+		if etag := c.ResponseRecorder.Header().Get("ETag"); etag != `"v1"` {
+			t.Fatalf("%s: expected quoted ETag but got %s", tc.name, etag)
+		}
+		if tc.fresh && c.ResponseRecorder.Code != 304 {
+			t.Fatalf("%s: expected HTTP 304 but got %d", tc.name, c.ResponseRecorder.Code)
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("If-None-Match", `"v1"`)
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r, nil)
+	if c.Fresh("v1", time.Time{}) {
+		t.Fatal("expected POST requests to never be fresh")
+	}
+}
@@ -0,0 +1,183 @@
+package seatbelt
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// The keys of the flash messages that hold a form's validation errors and
+// the values that were submitted with it, after a failed submission is
+// redirected back to the form.
+const (
+	flashErrors   = "errors"
+	flashOldInput = "old_input"
+)
+
+// maxOldInputSize is the maximum number of bytes of submitted values kept in
+// the session after a failed submission. Since the session is stored in a
+// cookie, which browsers limit to 4KB, fields that don't fit, ie, a long
+// textarea, aren't refilled after the redirect.
+const maxOldInputSize = 1024
+
+// RenderForm renders the form template with the given name after the
+// submitted form failed validation, with a 422 Unprocessable Entity status
+// unless another is given. Within the template, the field_error func returns
+// the first problem with the given field, and the old func returns the value
+// that was submitted for the given field, ie,
+//
+//	<input name="name" value="{{ old "name" }}">
+//	{{ with field_error "name" }}<p class="error">{{ . }}</p>{{ end }}
+//
+// If the given error isn't ValidationErrors, it's returned as-is, so that
+// it's handled by the error handler, ie,
+//
+//	if err := c.Bind(&product); err != nil {
+//		return c.RenderForm("products/new", product, err)
+//	}
+func (c *context) RenderForm(name string, data interface{}, err error, opts ...RenderOption) error {
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	c.formErrors = verrs
+	c.formInput = c.r.Form
+
+	var opt RenderOption
+	if len(opts) > 0 {
+		opt = opts[len(opts)-1]
+	}
+	if opt.Status == 0 {
+		opt.Status = http.StatusUnprocessableEntity
+	}
+
+	return c.Render(name, data, opt)
+}
+
+// flashForm stores the given validation errors, and the submitted form
+// values, as flash messages, so that they're available to the field_error
+// and old template funcs after redirecting back to the form. Sensitive
+// fields are never stored, and fields are skipped once maxOldInputSize
+// bytes of values have been stored.
+func (c *context) flashForm(verrs ValidationErrors) {
+	errs := make(map[string]interface{}, len(verrs))
+	for field, msgs := range verrs {
+		errs[field] = msgs
+	}
+	c.Session().Flash(flashErrors, errs)
+
+	// The handler may have returned before parsing the form, ie, if it
+	// validated a field by hand.
+	c.parseForm()
+	keys := make([]string, 0, len(c.r.Form))
+	for key := range c.r.Form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	input := make(map[string]interface{}, len(c.r.Form))
+	size := 0
+	for _, key := range keys {
+		if c.isSensitiveField(key) {
+			continue
+		}
+
+		values := c.r.Form[key]
+		n := len(key)
+		for _, v := range values {
+			n += len(v)
+		}
+		if size+n > maxOldInputSize {
+			continue
+		}
+		size += n
+
+		if len(values) == 1 {
+			input[key] = values[0]
+		} else {
+			input[key] = values
+		}
+	}
+	c.Session().Flash(flashOldInput, input)
+}
+
+// flashes returns the flash messages, which are read from the session the
+// first time they're needed, since reading them removes them from the
+// session.
+func (c *context) flashes() map[string]interface{} {
+	if !c.flashesRead {
+		c.flashMap = c.Session().Flashes()
+		c.flashesRead = true
+	}
+	return c.flashMap
+}
+
+// fieldError returns the first problem with the given field, either from
+// the form being rendered with RenderForm, or from the previous, redirected
+// request.
+func (c *context) fieldError(name string) string {
+	if c.formErrors != nil {
+		return c.formErrors.Get(name)
+	}
+
+	errs, _ := c.flashes()[flashErrors].(map[string]interface{})
+	switch msgs := errs[name].(type) {
+	case []string:
+		if len(msgs) > 0 {
+			return msgs[0]
+		}
+	case string:
+		return msgs
+	}
+	return ""
+}
+
+// old returns the value submitted for the given field, either to the form
+// being rendered with RenderForm, or to the previous, redirected request.
+func (c *context) old(name string) string {
+	if c.formInput != nil {
+		if c.isSensitiveField(name) {
+			return ""
+		}
+		return url.Values(c.formInput).Get(name)
+	}
+
+	input, _ := c.flashes()[flashOldInput].(map[string]interface{})
+	switch v := input[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// isSensitiveField returns true if the value of the given field must never
+// be sent back to the browser, ie, passwords, the CSRF token, uploaded
+// files, and the fields listed in the SensitiveFields option.
+func (c *context) isSensitiveField(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.Contains(lower, "password") || lower == "gorilla.csrf.token" {
+		return true
+	}
+
+	if mf := c.r.MultipartForm; mf != nil {
+		if _, ok := mf.File[name]; ok {
+			return true
+		}
+	}
+
+	if c.app != nil {
+		for _, field := range c.app.sensitiveFields {
+			if strings.EqualFold(name, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
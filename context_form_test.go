@@ -0,0 +1,209 @@
+package seatbelt_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+// account is used to test re-rendering forms that fail validation.
+type account struct {
+	Name     string `validate:"required"`
+	Email    string `validate:"required,email"`
+	Password string `validate:"required,min=8"`
+}
+
+// formTemplates are the templates of a form that shows each field's error
+// and previous input.
+var formTemplates = map[string]string{
+	"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+	"accounts/new.html": `{{ define "main" }}` +
+		`{{ with flashes }}{{ .alert }}|{{ end }}` +
+		`name={{ old "name" }}:{{ field_error "name" }}|` +
+		`email={{ old "email" }}:{{ field_error "email" }}|` +
+		`password={{ old "password" }}:{{ field_error "password" }}` +
+		`{{ end }}`,
+}
+
+func TestContextRenderForm(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New(seatbelt.Option{TemplateDir: writeTemplates(t, formTemplates)})
+	app.Post("/accounts", func(c seatbelt.Context) error {
+		var a account
+		if err := c.Bind(&a); err != nil {
+			return c.RenderForm("accounts/new", a, err)
+		}
+		return c.NoContent()
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.PostForm(srv.URL+"/accounts", url.Values{
+		"email":    {"not-an-email"},
+		"password": {"short"},
+	})
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%+v reading body", err)
+	}
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected HTTP 422 but got %d", resp.StatusCode)
+	}
+
+	expected := "name=:is required|email=not-an-email:must be a valid email address|password=:must be at least 8 characters"
+	if string(body) != expected {
+		t.Fatalf("expected %q but got %q", expected, body)
+	}
+}
+
+func TestErrorHandlerFlashesForm(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New(seatbelt.Option{
+		TemplateDir: writeTemplates(t, formTemplates),
+		// Reload disables secure cookies, so that the session is sent over
+		// plain HTTP.
+		Reload: true,
+	})
+	app.Get("/accounts/new", func(c seatbelt.Context) error {
+		return c.Render("accounts/new", nil)
+	})
+	app.Post("/accounts", func(c seatbelt.Context) error {
+		var a account
+		if err := c.Bind(&a); err != nil {
+			return err
+		}
+		return c.NoContent()
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("%+v creating cookie jar", err)
+	}
+	client := &http.Client{Jar: jar}
+
+	form := url.Values{
+		"name":     {"Ben"},
+		"email":    {"not-an-email"},
+		"password": {"hunter2"},
+	}
+	req, err := http.NewRequest("POST", srv.URL+"/accounts", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("%+v creating request", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", srv.URL+"/accounts/new")
+
+	// The client follows the redirect back to the form.
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%+v reading body", err)
+	}
+
+	expected := "email must be a valid email address; password must be at least 8 characters|" +
+		"name=Ben:|email=not-an-email:must be a valid email address|password=:must be at least 8 characters"
+	if string(body) != expected {
+		t.Fatalf("expected %q but got %q", expected, body)
+	}
+
+	// The flashes are only shown once.
+	resp, err = client.Get(srv.URL + "/accounts/new")
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%+v reading body", err)
+	}
+
+	if expected := "name=:|email=:|password=:"; string(body) != expected {
+		t.Fatalf("expected %q but got %q", expected, body)
+	}
+}
+
+func TestErrorHandlerFlashesFormSkipsSensitiveAndLargeFields(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New(seatbelt.Option{
+		TemplateDir: writeTemplates(t, map[string]string{
+			"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+			"profiles/edit.html": `{{ define "main" }}` +
+				`name={{ old "name" }}|bio={{ old "bio" }}|card={{ old "card_number" }}|avatar={{ old "avatar" }}` +
+				`{{ end }}`,
+		}),
+		SensitiveFields: []string{"card_number"},
+		Reload:          true,
+	})
+	app.Get("/profile", func(c seatbelt.Context) error {
+		return c.Render("profiles/edit", nil)
+	})
+	app.Post("/profile", func(c seatbelt.Context) error {
+		return seatbelt.ValidationErrors{"name": {"is taken"}}
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("%+v creating cookie jar", err)
+	}
+	client := &http.Client{Jar: jar}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("name", "Ben")
+	mw.WriteField("bio", strings.Repeat("a", 4096))
+	mw.WriteField("card_number", "4242424242424242")
+	fw, err := mw.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatalf("%+v creating form file", err)
+	}
+	fw.Write([]byte("not really a png"))
+	mw.Close()
+
+	req, err := http.NewRequest("POST", srv.URL+"/profile", &buf)
+	if err != nil {
+		t.Fatalf("%+v creating request", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Referer", srv.URL+"/profile")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%+v reading body", err)
+	}
+
+	if expected := "name=Ben|bio=|card=|avatar="; string(body) != expected {
+		t.Fatalf("expected %q but got %q", expected, body)
+	}
+}
@@ -0,0 +1,125 @@
+package seatbelt
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// fragmentBlock is the block that Render renders on its own, without the
+// layout, when responding to a fragment request.
+const fragmentBlock = "main"
+
+// IsHTMX returns true if the request was made by htmx.
+func (c *context) IsHTMX() bool {
+	return c.r.Header.Get("HX-Request") == "true"
+}
+
+// HXTarget returns the ID of the element htmx will swap the response into,
+// if there is one.
+func (c *context) HXTarget() string {
+	return c.r.Header.Get("HX-Target")
+}
+
+// TurboFrame returns the ID of the Turbo Frame that made the request, if one
+// did.
+func (c *context) TurboFrame() string {
+	return c.r.Header.Get("Turbo-Frame")
+}
+
+// isFragment returns true if the request only needs part of a page, rather
+// than the entire page including its layout.
+//
+// Boosted htmx requests and history restoration requests replace the entire
+// body, so they still receive the layout.
+func (c *context) isFragment() bool {
+	if c.TurboFrame() != "" {
+		return true
+	}
+
+	return c.IsHTMX() &&
+		c.r.Header.Get("HX-Boosted") != "true" &&
+		c.r.Header.Get("HX-History-Restore-Request") != "true"
+}
+
+// HXRedirect tells htmx to perform a full page redirect to the given url.
+// The returned error will always be nil.
+func (c *context) HXRedirect(url string) error {
+	c.w.Header().Set("HX-Redirect", url)
+	c.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// HXRefresh tells htmx to perform a full page refresh. The returned error
+// will always be nil.
+func (c *context) HXRefresh() error {
+	c.w.Header().Set("HX-Refresh", "true")
+	c.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// HXTrigger tells htmx to trigger the given client side events once the
+// response has been received. It must be called before the response is
+// written.
+func (c *context) HXTrigger(events ...string) {
+	if existing := c.w.Header().Get("HX-Trigger"); existing != "" {
+		events = append([]string{existing}, events...)
+	}
+	c.w.Header().Set("HX-Trigger", strings.Join(events, ", "))
+}
+
+// A TurboStream is a single Turbo Stream action, which tells Turbo to change
+// part of the current page.
+type TurboStream struct {
+	// Action is the action to perform, ie, "append", "prepend", "replace",
+	// "update", "remove", "before", or "after".
+	Action string
+
+	// Target is the ID of the element to perform the action on.
+	Target string
+
+	// Template is the name of the template to render within the action. It
+	// is rendered on its own without a layout, so it should be a partial.
+	// The template is optional, as some actions, like "remove", don't use
+	// one.
+	Template string
+
+	// Data is the data the template is rendered with.
+	Data interface{}
+}
+
+// TurboStream sends the given Turbo Stream actions with the given status
+// code.
+func (c *context) TurboStream(code int, streams ...TurboStream) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
+	buf := &bytes.Buffer{}
+
+	for _, stream := range streams {
+		buf.WriteString(`<turbo-stream action="` + template.HTMLEscapeString(stream.Action) +
+			`" target="` + template.HTMLEscapeString(stream.Target) + `">`)
+
+		if stream.Template != "" {
+			html, err := c.render.execute(c.r, stream.Template, c.templateData(stream.Data), c.funcs(), newRenderOption([]RenderOption{{
+				Block: stream.Template,
+			}}))
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString("<template>")
+			html.WriteTo(buf)
+			buf.WriteString("</template>")
+		}
+
+		buf.WriteString("</turbo-stream>")
+	}
+
+	c.w.Header().Set("Content-Type", "text/vnd.turbo-stream.html")
+	c.w.WriteHeader(code)
+	_, err := buf.WriteTo(c.w)
+	return err
+}
@@ -0,0 +1,120 @@
+package seatbelt_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestContextRenderFragment(t *testing.T) {
+	cases := []struct {
+		name         string
+		headers      map[string]string
+		expectLayout bool
+	}{
+		{
+			name:         "regular request",
+			expectLayout: true,
+		},
+		{
+			name:         "htmx request",
+			headers:      map[string]string{"HX-Request": "true", "HX-Target": "content"},
+			expectLayout: false,
+		},
+		{
+			name:         "boosted htmx request",
+			headers:      map[string]string{"HX-Request": "true", "HX-Boosted": "true"},
+			expectLayout: true,
+		},
+		{
+			name:         "turbo frame request",
+			headers:      map[string]string{"Turbo-Frame": "content"},
+			expectLayout: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, val := range tc.headers {
+				r.Header.Set(key, val)
+			}
+
+			c := seatbelt.NewTestContext(w, r)
+			c.AddRenderer("testdata", template.FuncMap{
+				"lower": strings.ToLower,
+			})
+
+			if err := c.Render("home/index", nil); err != nil {
+				t.Fatalf("%+v rendering template", err)
+			}
+
+			body := w.Body.String()
+			if !strings.Contains(body, "<h1>Home</h1>") {
+				t.Fatalf("expected main block in %s", body)
+			}
+			if hasLayout := strings.Contains(body, "<html"); hasLayout != tc.expectLayout {
+				t.Fatalf("expected layout to be %t in %s", tc.expectLayout, body)
+			}
+			if c.HXTarget() != tc.headers["HX-Target"] || c.TurboFrame() != tc.headers["Turbo-Frame"] {
+				t.Fatalf("expected request headers to be detected")
+			}
+		})
+	}
+}
+
+func TestContextHTMXResponseHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("HX-Request", "true")
+
+	c := seatbelt.NewTestContext(w, r)
+	if !c.IsHTMX() {
+		t.Fatalf("expected htmx request")
+	}
+
+	c.HXTrigger("saved")
+	c.HXTrigger("closeModal")
+	if err := c.HXRedirect("/products"); err != nil {
+		t.Fatalf("%+v redirecting", err)
+	}
+
+	if trigger := w.Header().Get("HX-Trigger"); trigger != "saved, closeModal" {
+		t.Fatalf("expected both events to be triggered but got %s", trigger)
+	}
+	if redirect := w.Header().Get("HX-Redirect"); redirect != "/products" {
+		t.Fatalf("expected HX-Redirect to /products but got %s", redirect)
+	}
+}
+
+func TestContextTurboStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	c := seatbelt.NewTestContext(w, r)
+	c.AddRenderer("testdata", template.FuncMap{
+		"lower": strings.ToLower,
+	})
+
+	if err := c.TurboStream(200,
+		seatbelt.TurboStream{Action: "append", Target: "list", Template: "home/funcpartial"},
+		seatbelt.TurboStream{Action: "remove", Target: `item"1`},
+	); err != nil {
+		t.Fatalf("%+v rendering turbo stream", err)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/vnd.turbo-stream.html" {
+		t.Fatalf("expected turbo stream content type but got %s", ct)
+	}
+
+	const expected = `<turbo-stream action="append" target="list"><template><p>hey</p>
+</template></turbo-stream><turbo-stream action="remove" target="item&#34;1"></turbo-stream>`
+	if body := w.Body.String(); body != expected {
+		t.Fatalf("expected %s but got %s", expected, body)
+	}
+}
@@ -0,0 +1,243 @@
+package seatbelt
+
+import (
+	sctx "context"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// negotiatedTypes maps the short format names understood by Negotiate and
+// Accepts to their media types.
+var negotiatedTypes = map[string]string{
+	"html": "text/html",
+	"json": "application/json",
+	"xml":  "application/xml",
+	"text": "text/plain",
+}
+
+// formatPreference is the order formats are preferred in when the client
+// accepts more than one equally, ie, when it accepts anything.
+var formatPreference = []string{"html", "json", "xml", "text"}
+
+// Negotiate calls the func for the format that best matches what the client
+// accepts, and returns its error. The keys of the given map are either short
+// format names, "html", "json", "xml", or "text", or full media types, ie,
+//
+//	return c.Negotiate(map[string]func() error{
+//		"html": func() error { return c.Render("products/show", product) },
+//		"json": func() error { return c.JSON(200, product) },
+//	})
+//
+// A format given as the extension of the request's path, such as
+// "/products/1.json", takes precedence over the Accept header. If the path
+// doesn't match any route with its extension, the extension is removed
+// before the request is routed, so "/products/1.json" matches the route
+// "/products/{id:[0-9]+}", and its id path param is "1". A route like
+// "/products/{id}" matches the path as it is, so its id would be "1.json".
+// If none of the formats are acceptable, a 406 Not Acceptable HTTPError is
+// returned.
+func (c *context) Negotiate(offers map[string]func() error) error {
+	// The response depends on the Accept header, so caches must store a
+	// separate response for each value of it.
+	c.w.Header().Add("Vary", "Accept")
+
+	formats := make([]string, 0, len(offers))
+	for format := range offers {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool {
+		pi, pj := preference(formats[i]), preference(formats[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return formats[i] < formats[j]
+	})
+
+	format := c.Accepts(formats...)
+	if format == "" {
+		return NewHTTPError(http.StatusNotAcceptable)
+	}
+	return offers[format]()
+}
+
+// preference returns the rank of the given format in the preferred order.
+func preference(format string) int {
+	for i, f := range formatPreference {
+		if f == format {
+			return i
+		}
+	}
+	return len(formatPreference)
+}
+
+// Accepts returns the format, out of the given formats, that best matches
+// what the client accepts, or an empty string if none of them do. When the
+// client accepts more than one format equally, the earliest is returned.
+//
+// Like Negotiate, formats are short format names or full media types, and
+// the extension of the request's path takes precedence over the Accept
+// header.
+func (c *context) Accepts(formats ...string) string {
+	if len(formats) == 0 {
+		return ""
+	}
+
+	// A known format in the path's extension must be one of the given
+	// formats, regardless of what the Accept header says. The extension
+	// has usually been removed before routing, but is kept when a route
+	// includes it, ie, "/feed.xml".
+	ext := c.pathFormat()
+	if ext == "" {
+		ext = strings.TrimPrefix(path.Ext(c.r.URL.Path), ".")
+	}
+	if ext != "" {
+		if _, ok := negotiatedTypes[ext]; ok {
+			for _, format := range formats {
+				if format == ext {
+					return format
+				}
+			}
+			return ""
+		}
+	}
+
+	ranges := parseAccept(c.r.Header.Get("Accept"))
+	if len(ranges) == 0 {
+		return formats[0]
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, format := range formats {
+		mediaType := format
+		if t, ok := negotiatedTypes[format]; ok {
+			mediaType = t
+		}
+
+		if q := acceptQuality(ranges, mediaType); q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// pathFormatKey is the context key of the format that was removed from the
+// extension of the request's path by stripFormat.
+type pathFormatKey struct{}
+
+// pathFormat returns the format that was removed from the extension of the
+// request's path before it was routed, or an empty string if there wasn't
+// one.
+func (c *context) pathFormat() string {
+	format, _ := c.r.Context().Value(pathFormatKey{}).(string)
+	return format
+}
+
+// stripFormat returns the given request with the extension of a known format
+// removed from its path, so that the extension isn't part of the last path
+// param, ie, "/products/1.json" is routed as "/products/1". The format is
+// saved on the request's context for Negotiate and Accepts.
+//
+// The extension is only removed if the path doesn't match any route as it
+// is, and does once it's removed. So the routes "/feed.xml" and
+// "/files/{name}", and a FileServer, all get the path with its extension.
+func (a *App) stripFormat(r *http.Request) *http.Request {
+	ext := path.Ext(r.URL.Path)
+	format := strings.TrimPrefix(ext, ".")
+	if _, ok := negotiatedTypes[format]; !ok {
+		return r
+	}
+
+	stripped := strings.TrimSuffix(r.URL.Path, ext)
+	if stripped == "" || strings.HasSuffix(stripped, "/") {
+		return r
+	}
+	if a.match(r.Method, r.URL.Path) || !a.match(r.Method, stripped) {
+		return r
+	}
+
+	u := *r.URL
+	u.Path = stripped
+	if strings.HasSuffix(u.RawPath, ext) {
+		u.RawPath = strings.TrimSuffix(u.RawPath, ext)
+	} else {
+		u.RawPath = ""
+	}
+
+	r = r.WithContext(sctx.WithValue(r.Context(), pathFormatKey{}, format))
+	r.URL = &u
+	return r
+}
+
+// match returns true if a route matches the given method and path. HEAD
+// requests also match GET routes, as they do when they're served.
+func (a *App) match(method, path string) bool {
+	if a.mux.Match(chi.NewRouteContext(), method, path) {
+		return true
+	}
+	return method == http.MethodHead && a.mux.Match(chi.NewRouteContext(), http.MethodGet, path)
+}
+
+// An acceptRange is a single media range from an Accept header.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses the media ranges and their quality values from the
+// given Accept header.
+func parseAccept(header string) []acceptRange {
+	ranges := make([]acceptRange, 0)
+
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	return ranges
+}
+
+// acceptQuality returns the quality value of the most specific media range
+// that matches the given media type, or zero if none of them do.
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	typ := strings.SplitN(mediaType, "/", 2)[0]
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mediaType == mediaType:
+			s = 2
+		case r.mediaType == typ+"/*":
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		}
+
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+
+	return q
+}
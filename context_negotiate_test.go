@@ -0,0 +1,138 @@
+package seatbelt_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestContextNegotiate(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New()
+
+	handler := func(c seatbelt.Context) error {
+		return c.Negotiate(map[string]func() error{
+			"html": func() error { return c.String(200, "html") },
+			"json": func() error { return c.JSON(200, "json") },
+		})
+	}
+	app.Get("/product", handler)
+	app.Get("/product.json", handler)
+	app.Get("/product.xml", handler)
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	cases := []struct {
+		name   string
+		path   string
+		accept string
+		code   int
+		body   string
+	}{
+		{name: "no accept header", path: "/product", code: 200, body: "html"},
+		{name: "browser", path: "/product", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", code: 200, body: "html"},
+		{name: "json", path: "/product", accept: "application/json", code: 200, body: `"json"`},
+		{name: "quality values", path: "/product", accept: "text/html;q=0.5, application/json;q=0.9", code: 200, body: `"json"`},
+		{name: "wildcard subtype", path: "/product", accept: "application/*", code: 200, body: `"json"`},
+		{name: "excluded", path: "/product", accept: "text/html;q=0, */*", code: 200, body: `"json"`},
+		{name: "suffix", path: "/product.json", accept: "text/html", code: 200, body: `"json"`},
+		{name: "unacceptable suffix", path: "/product.xml", code: 406, body: "Not Acceptable"},
+		{name: "unacceptable", path: "/product", accept: "image/png", code: 406, body: "Not Acceptable"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+tc.path, nil)
+			if err != nil {
+				t.Fatalf("%+v creating new http request", err)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%+v executing http request", err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("%+v reading body", err)
+			}
+
+			if resp.StatusCode != tc.code {
+				t.Fatalf("expected %d but got %d", tc.code, resp.StatusCode)
+			}
+			if string(body) != tc.body {
+				t.Fatalf("expected body %s but got %s", tc.body, body)
+			}
+			if vary := resp.Header.Get("Vary"); vary != "Accept" {
+				t.Fatalf("expected Vary: Accept but got %s", vary)
+			}
+		})
+	}
+}
+
+func TestContextNegotiatePathParam(t *testing.T) {
+	t.Parallel()
+
+	static := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(static, "data.json"), []byte(`{"static":true}`), 0644); err != nil {
+		t.Fatalf("%+v writing static file", err)
+	}
+
+	app := seatbelt.New()
+	app.Get("/products/{id:[0-9]+}", func(c seatbelt.Context) error {
+		id := c.PathParam("id")
+		return c.Negotiate(map[string]func() error{
+			"html": func() error { return c.String(200, "html "+id) },
+			"json": func() error { return c.JSON(200, id) },
+		})
+	})
+	app.Get("/files/{name}", func(c seatbelt.Context) error {
+		return c.String(200, c.PathParam("name"))
+	})
+	app.Get("/feed.xml", func(c seatbelt.Context) error {
+		return c.Negotiate(map[string]func() error{
+			"xml": func() error { return c.String(200, "feed") },
+		})
+	})
+	app.Get("/products/export.json", func(c seatbelt.Context) error {
+		return c.String(200, "export")
+	})
+	app.FileServer("/static", static)
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{path: "/products/5", code: 200, body: "html 5"},
+		{path: "/products/5.json", code: 200, body: `"5"`},
+		{path: "/products/5.xml", code: 406, body: "Not Acceptable"},
+		{path: "/products/abc.json", code: 404},
+		{path: "/products/export.json", code: 200, body: "export"},
+		{path: "/feed.xml", code: 200, body: "feed"},
+		{path: "/files/report.json", code: 200, body: "report.json"},
+		{path: "/static/data.json", code: 200, body: `{"static":true}`},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		if w.Code != tc.code {
+			t.Fatalf("expected HTTP %d for %s but got %d", tc.code, tc.path, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); tc.body != "" && body != tc.body {
+			t.Fatalf("expected %q for %s but got %q", tc.body, tc.path, body)
+		}
+	}
+}
@@ -1,14 +1,20 @@
 package seatbelt
 
 import (
-	"encoding/json"
+	"mime"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi"
 	"github.com/mitchellh/mapstructure"
 )
 
+// defaultMaxMemory is the maximum number of bytes of a multipart form that
+// are held in memory, rather than in temporary files.
+const defaultMaxMemory = 32 << 20
+
 // Params mass-assigns query, path, and form parameters to the given struct or
 // map.
 //
@@ -22,43 +28,148 @@ import (
 //
 // For POST, PUT, and PATCH requests, the body will be read. For any other
 // request, it will not.
-func (c *context) Params(v interface{}) error {
-	// TODO: Consider if c.r.ParseMultipartForm is prefereable, as it calls
-	// ParseForm anyway and runs only once instead of being idempotent.
-	if err := c.r.ParseForm(); err != nil {
+//
+// A param with multiple values, ie, tags=a&tags=b, is decoded into a slice,
+// and brackets in a param's name decode into nested structs, maps, and
+// slices, ie,
+//
+//	address[city]=Toronto&items[0][name]=Widget&items[1][name]=Gadget
+//
+// decodes into,
+//
+//	type Order struct {
+//		Address struct {
+//			City string
+//		}
+//		Items []struct {
+//			Name string
+//		}
+//	}
+//
+// When a param with multiple values is decoded into a field that only holds
+// one, the last value is used.
+//
+// Files uploaded with a multipart form are decoded into
+// *multipart.FileHeader and []*multipart.FileHeader fields.
+//
+// Params are decoded into time.Time fields from RFC 3339 timestamps, or the
+// values of HTML date, datetime-local, and time inputs, into time.Duration
+// fields with time.ParseDuration, and into any type that implements
+// encoding.TextUnmarshaler, such as net.IP. Bool fields accept "on", the
+// value of a checked checkbox. Decoders for other types can be registered
+// with RegisterDecoder.
+//
+// A JSON body, sent with the application/json content type, is decoded
+// directly into a struct, using each field's json tag, or its params tag if
+// it doesn't have one. If a field in the body has the wrong type, the
+// returned error is ValidationErrors, and if the body is larger than the
+// MaxBodySize option, the returned HTTPError wraps ErrBodyTooLarge.
+//
+// A field tagged params:"-" is never assigned, and the Permit option limits
+// the params that are assigned to the ones it lists, so that a user can't
+// assign a field that the form doesn't contain, ie, an admin flag,
+//
+//	c.Params(&user, seatbelt.Permit("name", "email"))
+//
+// Params that may not be assigned are ignored, or if the
+// RejectUnpermittedParams option is set, returned as ValidationErrors. Path
+// params are always assigned, since their names come from the route.
+//
+// A field with a query, header, or path tag is only assigned from that
+// source, using the name in the tag, ie, a field tagged header:"X-Tenant" is
+// assigned the X-Tenant request header. To assign params from a single
+// source, use BindQuery, BindForm, BindPath, BindJSON, or BindHeader.
+func (c *context) Params(v interface{}, opts ...ParamsOption) error {
+	if err := c.parseForm(); err != nil {
 		return err
 	}
 
-	// mapstructure doesn't like the map[string][]string that the query and
-	// form data is in, so we turn it into a map[string]string.
 	values := make(map[string]interface{})
 
-	// Parse the body query parameters using the built-in Form map, as calling
-	// ParseForm() already does what we want to do.
-	for key, val := range c.r.Form {
-		values[key] = strings.Join(val, "")
+	// Set the query params first, so that body params with the same name
+	// take precedence.
+	for key, val := range c.r.URL.Query() {
+		setParam(values, key, val)
+	}
+	for key, val := range c.r.PostForm {
+		setParam(values, key, val)
+	}
+	if c.r.MultipartForm != nil {
+		for key, files := range c.r.MultipartForm.File {
+			setFiles(values, key, files)
+		}
 	}
+	collapseIndexes(values)
+
+	permit := newPermitTree(opts)
 
 	// Parse the JSON body if the content type and HTTP verb correct.
-	if c.r.Method == "POST" || c.r.Method == "PUT" || c.r.Method == "PATCH" {
-		if c.r.Header.Get("Content-Type") == "application/json" {
-			if err := json.NewDecoder(c.r.Body).Decode(&values); err != nil {
-				return err
-			}
-			defer c.r.Body.Close()
-		}
+	if c.isJSON() {
+		return c.paramsJSON(values, v, permit)
 	}
 
-	// Finally, overwrite any values with path params.
+	if err := c.unpermitted(filterParams(values, reflect.TypeOf(v), permit, paramsFieldName, "")); err != nil {
+		return err
+	}
+	return c.decodeWithPath(values, v)
+}
+
+// paramsJSON assigns the JSON request body to v, as Params does, after the
+// given query params.
+func (c *context) paramsJSON(values map[string]interface{}, v interface{}, permit permitTree) error {
+	data, err := c.readJSONParams(values, v, permit)
+	if err != nil {
+		return err
+	}
+	if !isStructPtr(v) {
+		return c.decodeWithPath(values, v)
+	}
+
+	// Structs are decoded directly from the JSON, between the query and
+	// path params, so that they keep the JSON's precision and types.
+	c.bindSources(values, reflect.TypeOf(v), sourceTags...)
+	if err := c.decode(values, v); err != nil {
+		return err
+	}
+	if err := decodeJSONStruct(data, v, c.disallowUnknownFields()); err != nil {
+		return err
+	}
+	return c.decodeWithPath(make(map[string]interface{}), v)
+}
+
+// decodeWithPath decodes the given params into v, after overwriting them
+// with the path params, and assigning the fields that have source tags.
+func (c *context) decodeWithPath(values map[string]interface{}, v interface{}) error {
+	for key, val := range c.pathParams() {
+		values[key] = val
+	}
+	c.bindSources(values, reflect.TypeOf(v), sourceTags...)
+
+	return c.decode(values, v)
+}
+
+// pathParams returns the path params of the request's route.
+func (c *context) pathParams() map[string]interface{} {
+	params := make(map[string]interface{})
 	if rctx := chi.RouteContext(c.r.Context()); rctx != nil {
 		for i, key := range rctx.URLParams.Keys {
-			values[key] = rctx.URLParams.Values[i]
+			params[key] = rctx.URLParams.Values[i]
 		}
 	}
+	return params
+}
 
+// decode decodes the given params into v.
+func (c *context) decode(values map[string]interface{}, v interface{}) error {
 	// The config below is the same as mapstructure's `WeakDecode`, but with
 	// the tag name "params" instead of "mapstructure".
+	var decoders map[reflect.Type]DecoderFunc
+	if c.app != nil {
+		decoders = c.app.decoders
+	}
+
 	config := &mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(lastValueHook, typeHook(decoders)),
 		Metadata:         nil,
 		Result:           v,
 		WeaklyTypedInput: true,
@@ -73,6 +184,56 @@ func (c *context) Params(v interface{}) error {
 	return decoder.Decode(values)
 }
 
+// isStructPtr returns true if v is a pointer to a struct.
+func isStructPtr(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
+}
+
+// parseForm parses the query and the body of the request, including
+// multipart bodies, up to the MaxUploadSize option.
+func (c *context) parseForm() error {
+	mediaType, _, _ := mime.ParseMediaType(c.r.Header.Get("Content-Type"))
+	multipart := mediaType == "multipart/form-data"
+	if (multipart && c.r.MultipartForm != nil) || (!multipart && c.r.PostForm != nil) {
+		return nil
+	}
+
+	var body *limitedBody
+	if max := c.maxUploadSize(); max > 0 && c.r.Body != nil && c.r.Body != http.NoBody {
+		if c.r.ContentLength > max {
+			return tooLargeError(ErrUploadTooLarge)
+		}
+		body = &limitedBody{ReadCloser: c.r.Body, remaining: max, err: tooLargeError(ErrUploadTooLarge)}
+		c.r.Body = body
+	}
+
+	var err error
+	if multipart {
+		err = c.r.ParseMultipartForm(c.maxMemory())
+		c.forms.add(c.r.MultipartForm)
+	} else {
+		err = c.r.ParseForm()
+	}
+
+	if err != nil && body != nil && body.exceeded {
+		return tooLargeError(ErrUploadTooLarge)
+	}
+	return err
+}
+
+// Bind mass-assigns query, path, and form parameters to the given struct,
+// as Params does, and then validates it, as Validate does, including any
+// validators registered with the application. If the struct is invalid, the
+// returned error is ValidationErrors. The given options are passed to
+// Params.
+func (c *context) Bind(v interface{}, opts ...ParamsOption) error {
+	if err := c.Params(v, opts...); err != nil {
+		return err
+	}
+	return c.validate(v)
+}
+
 // Request returns the *http.Request for the current Context.
 func (c *context) Request() *http.Request {
 	return c.r
@@ -92,3 +253,70 @@ func (c *context) FormValue(name string) string {
 func (c *context) QueryParam(name string) string {
 	return c.r.URL.Query().Get(name)
 }
+
+// PathParamInt returns the path param with the given name as an int. If it
+// isn't an integer, an HTTPError with the status 404 Not Found is returned,
+// since the path can't refer to anything, ie,
+//
+//	id, err := c.PathParamInt("id")
+//	if err != nil {
+//		return err
+//	}
+//
+// Route patterns can use the int constraint, ie, /products/{id:int}, so that
+// the handler isn't called at all.
+func (c *context) PathParamInt(name string) (int, error) {
+	i, err := strconv.Atoi(c.PathParam(name))
+	if err != nil {
+		return 0, &HTTPError{Code: http.StatusNotFound, Err: err}
+	}
+	return i, nil
+}
+
+// QueryParamDefault returns the URL query parameter with the given name, or
+// the given default if it's absent or empty.
+func (c *context) QueryParamDefault(name, def string) string {
+	if value := c.QueryParam(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// QueryParamInt returns the URL query parameter with the given name as an
+// int, or the given default if it's absent or empty. If it isn't an
+// integer, an HTTPError with the status 400 Bad Request is returned.
+func (c *context) QueryParamInt(name string, def int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return def, &HTTPError{Code: http.StatusBadRequest, Message: "the query param " + name + " must be an integer", Err: err}
+	}
+	return i, nil
+}
+
+// QueryParamBool returns the URL query parameter with the given name as a
+// bool, or the given default if it's absent or empty. As well as the values
+// accepted by strconv.ParseBool, "on", "yes", "off", and "no" are accepted.
+// If it isn't a bool, an HTTPError with the status 400 Bad Request is
+// returned.
+func (c *context) QueryParamBool(name string, def bool) (bool, error) {
+	value := c.QueryParam(name)
+	switch strings.ToLower(value) {
+	case "":
+		return def, nil
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return def, &HTTPError{Code: http.StatusBadRequest, Message: "the query param " + name + " must be true or false", Err: err}
+	}
+	return b, nil
+}
@@ -1,8 +1,16 @@
 package seatbelt_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -110,3 +118,296 @@ func TestContextRequestParams(t *testing.T) {
 		tc.testfunc(t, v)
 	}
 }
+
+// order is used to test decoding multi-value and nested params.
+type order struct {
+	Tags    []string
+	Numbers []int
+	Note    string
+	Address struct {
+		City string
+	}
+	Items []struct {
+		Name     string
+		Quantity int
+	}
+	Meta map[string]string
+}
+
+func TestContextRequestParamsNested(t *testing.T) {
+	form := url.Values{
+		"tags":               {"a", "b"},
+		"numbers[]":          {"7"},
+		"note":               {"body"},
+		"address[city]":      {"Toronto"},
+		"items[1][name]":     {"Gadget"},
+		"items[0][name]":     {"Widget"},
+		"items[0][quantity]": {"2"},
+		"meta[color]":        {"red"},
+	}
+	query := "?note=query&tags=ignored&numbers=1"
+
+	urlencoded := func() *http.Request {
+		r := httptest.NewRequest("POST", "/"+query, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	multipartForm := func() *http.Request {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		for key, values := range form {
+			for _, value := range values {
+				mw.WriteField(key, value)
+			}
+		}
+		mw.Close()
+
+		r := httptest.NewRequest("POST", "/"+query, body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		return r
+	}
+
+	for name, newRequest := range map[string]func() *http.Request{
+		"urlencoded": urlencoded,
+		"multipart":  multipartForm,
+	} {
+		c := seatbelt.NewTestContext(httptest.NewRecorder(), newRequest(), nil)
+
+		var o order
+		if err := c.Params(&o); err != nil {
+			t.Fatalf("%s: %+v decoding params", name, err)
+		}
+
+		if !reflect.DeepEqual(o.Tags, []string{"a", "b"}) {
+			t.Fatalf("%s: expected tags [a b] but got %v", name, o.Tags)
+		}
+		if !reflect.DeepEqual(o.Numbers, []int{7}) {
+			t.Fatalf("%s: expected numbers [7] but got %v", name, o.Numbers)
+		}
+		if o.Note != "body" {
+			t.Fatalf("%s: expected body params to take precedence but got %s", name, o.Note)
+		}
+		if o.Address.City != "Toronto" {
+			t.Fatalf("%s: expected city Toronto but got %s", name, o.Address.City)
+		}
+		if len(o.Items) != 2 || o.Items[0].Name != "Widget" || o.Items[0].Quantity != 2 || o.Items[1].Name != "Gadget" {
+			t.Fatalf("%s: expected items in index order but got %+v", name, o.Items)
+		}
+		if o.Meta["color"] != "red" {
+			t.Fatalf("%s: expected meta color red but got %v", name, o.Meta)
+		}
+	}
+}
+
+func TestContextRequestParamsLastValue(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?name=first&name=last", nil)
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r, nil)
+
+	var v struct {
+		Name string
+	}
+	if err := c.Params(&v); err != nil {
+		t.Fatalf("%+v decoding params", err)
+	}
+	if v.Name != "last" {
+		t.Fatalf("expected the last value but got %s", v.Name)
+	}
+}
+
+func TestContextRequestParamsJSON(t *testing.T) {
+	type line struct {
+		SKU string `json:"sku"`
+	}
+	type invoice struct {
+		ID       string `params:"id"`
+		Customer string `json:"customer_name"`
+		Email    string `params:"email_address"`
+		Total    int64
+		Note     string
+		Lines    []line `json:"lines"`
+		Extra    interface{}
+	}
+
+	newContext := func(contentType, body string) *seatbelt.TestContext {
+		r := httptest.NewRequest("POST", "/?note=query&total=1", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		return seatbelt.NewTestContext(httptest.NewRecorder(), r, map[string]string{"id": "path"})
+	}
+
+	t.Run("decodes structs directly", func(t *testing.T) {
+		c := newContext("application/json; charset=utf-8", `{
+			"id": "body",
+			"customer_name": "Ben",
+			"email_address": "ben@example.com",
+			"total": 9007199254740993,
+			"lines": [{"sku": "A-1"}],
+			"extra": 12345678901234567890
+		}`)
+
+		var v invoice
+		if err := c.Params(&v); err != nil {
+			t.Fatalf("%+v decoding params", err)
+		}
+
+		if v.ID != "path" {
+			t.Fatalf("expected path params to take precedence but got %s", v.ID)
+		}
+		if v.Customer != "Ben" || v.Email != "ben@example.com" {
+			t.Fatalf("expected json and params tags to be used but got %+v", v)
+		}
+		if v.Total != 9007199254740993 {
+			t.Fatalf("expected total to keep its precision but got %d", v.Total)
+		}
+		if v.Note != "query" {
+			t.Fatalf("expected query params to be decoded but got %s", v.Note)
+		}
+		if len(v.Lines) != 1 || v.Lines[0].SKU != "A-1" {
+			t.Fatalf("expected nested lines but got %+v", v.Lines)
+		}
+		if n, ok := v.Extra.(json.Number); !ok || n.String() != "12345678901234567890" {
+			t.Fatalf("expected extra to be a json.Number but got %#v", v.Extra)
+		}
+	})
+
+	t.Run("reports type errors per field", func(t *testing.T) {
+		c := newContext("application/vnd.api+json", `{"total": "ten", "lines": [{"sku": 1}]}`)
+
+		var v invoice
+		var verrs seatbelt.ValidationErrors
+		if err := c.Params(&v); !errors.As(err, &verrs) {
+			t.Fatalf("expected ValidationErrors but got %v", err)
+		}
+
+		expected := seatbelt.ValidationErrors{
+			"total":         {"must be an integer"},
+			"lines[0][sku]": {"must be a string"},
+		}
+		if !reflect.DeepEqual(verrs, expected) {
+			t.Fatalf("expected %v but got %v", expected, verrs)
+		}
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		c := newContext("application/json", `{"total":`)
+
+		var v invoice
+		var herr *seatbelt.HTTPError
+		if err := c.Params(&v); !errors.As(err, &herr) || herr.Code != 400 {
+			t.Fatalf("expected HTTP 400 error but got %v", err)
+		}
+	})
+
+	t.Run("decodes maps", func(t *testing.T) {
+		c := newContext("application/json", `{"total": 9007199254740993}`)
+
+		v := make(map[string]interface{})
+		if err := c.Params(&v); err != nil {
+			t.Fatalf("%+v decoding params", err)
+		}
+		if n, ok := v["total"].(json.Number); !ok || n.String() != "9007199254740993" {
+			t.Fatalf("expected total to keep its precision but got %#v", v["total"])
+		}
+	})
+}
+
+func TestContextRequestParamsJSONLimits(t *testing.T) {
+	app := seatbelt.New(seatbelt.Option{
+		MaxBodySize:           48,
+		DisallowUnknownFields: true,
+	})
+	app.SetErrorHandler(func(c seatbelt.Context, err error) {
+		code := http.StatusInternalServerError
+		var herr *seatbelt.HTTPError
+		var verrs seatbelt.ValidationErrors
+		switch {
+		case errors.As(err, &herr):
+			code = herr.Code
+		case errors.As(err, &verrs):
+			code = http.StatusUnprocessableEntity
+		}
+		if code == http.StatusRequestEntityTooLarge && !errors.Is(err, seatbelt.ErrBodyTooLarge) {
+			code = http.StatusInternalServerError
+		}
+		c.String(code, err.Error())
+	})
+	app.Post("/", func(c seatbelt.Context) error {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := c.Params(&v); err != nil {
+			return err
+		}
+		return c.String(200, v.Name)
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	cases := []struct {
+		body string
+		code int
+		resp string
+	}{
+		{body: `{"name": "seatbelt"}`, code: 200, resp: "seatbelt"},
+		{body: `{"name": "seatbelt", "admin": true}`, code: 422, resp: "admin is not allowed"},
+		{body: `{"name": "` + strings.Repeat("a", 64) + `"}`, code: 413, resp: "the request body is too large"},
+	}
+
+	for _, tc := range cases {
+		resp, err := http.Post(srv.URL+"/", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("%+v executing http request", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.code || string(body) != tc.resp {
+			t.Fatalf("expected HTTP %d with %q but got %d with %q", tc.code, tc.resp, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestContextRequestTypedParams(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?page=3&draft=on&public=false&q=&bad=x", nil)
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r, map[string]string{
+		"id":   "42",
+		"slug": "hello",
+	})
+
+	if id, err := c.PathParamInt("id"); err != nil || id != 42 {
+		t.Fatalf("expected 42 but got %d, %v", id, err)
+	}
+	_, err := c.PathParamInt("slug")
+	if herr, ok := err.(*seatbelt.HTTPError); !ok || herr.Code != http.StatusNotFound {
+		t.Fatalf("expected HTTP 404 error but got %v", err)
+	}
+
+	if page, err := c.QueryParamInt("page", 1); err != nil || page != 3 {
+		t.Fatalf("expected page 3 but got %d, %v", page, err)
+	}
+	if perPage, err := c.QueryParamInt("per_page", 20); err != nil || perPage != 20 {
+		t.Fatalf("expected default of 20 but got %d, %v", perPage, err)
+	}
+	_, err = c.QueryParamInt("bad", 1)
+	if herr, ok := err.(*seatbelt.HTTPError); !ok || herr.Code != http.StatusBadRequest {
+		t.Fatalf("expected HTTP 400 error but got %v", err)
+	}
+
+	if draft, err := c.QueryParamBool("draft", false); err != nil || !draft {
+		t.Fatalf("expected draft to be true but got %t, %v", draft, err)
+	}
+	if public, err := c.QueryParamBool("public", true); err != nil || public {
+		t.Fatalf("expected public to be false but got %t, %v", public, err)
+	}
+	if _, err := c.QueryParamBool("bad", false); err == nil {
+		t.Fatalf("expected an error for an invalid bool")
+	}
+
+	if q := c.QueryParamDefault("q", "all"); q != "all" {
+		t.Fatalf("expected default of all but got %s", q)
+	}
+	if page := c.QueryParamDefault("page", "1"); page != "3" {
+		t.Fatalf("expected 3 but got %s", page)
+	}
+}
@@ -2,16 +2,26 @@ package seatbelt
 
 import (
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
 	"net/http"
+	"os"
+	"strings"
 )
 
-// Response returns the http.ResponseWriter for the current Context.
-func (c *context) Response() http.ResponseWriter {
+// Response returns the ResponseWriter for the current Context.
+func (c *context) Response() ResponseWriter {
 	return c.w
 }
 
 // String sends a string response with the given status code.
 func (c *context) String(code int, s string) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
 	c.w.Header().Set("Content-Type", "text/plain")
 	c.w.WriteHeader(code)
 	_, err := c.w.Write([]byte(s))
@@ -20,32 +30,240 @@ func (c *context) String(code int, s string) error {
 
 // JSON sends a JSON response with the given status code.
 func (c *context) JSON(code int, v interface{}) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
 	c.w.Header().Set("Content-Type", "application/json")
+	if code == http.StatusOK && c.etagEnabled() && setETag(c.w, c.r, data) {
+		return nil
+	}
 	c.w.WriteHeader(code)
 
-	data, err := json.Marshal(v)
+	_, err = c.w.Write(data)
+	return err
+}
+
+// XML sends an XML response with the given status code.
+func (c *context) XML(code int, v interface{}) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
+	data, err := xml.Marshal(v)
 	if err != nil {
 		return err
 	}
 
+	c.w.Header().Set("Content-Type", "application/xml")
+	c.w.WriteHeader(code)
+
+	if _, err := c.w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
 	_, err = c.w.Write(data)
 	return err
 }
 
-// NoContent sends a 204 No Content HTTP response. It will always return a nil
-// error.
+// Blob sends a response with the given status code, content type, and body.
+func (c *context) Blob(code int, contentType string, b []byte) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
+	c.w.Header().Set("Content-Type", contentType)
+	c.w.WriteHeader(code)
+	_, err := c.w.Write(b)
+	return err
+}
+
+// Stream sends a response with the given status code and content type,
+// copying the body from the given reader until it's exhausted.
+func (c *context) Stream(code int, contentType string, r io.Reader) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
+	c.w.Header().Set("Content-Type", contentType)
+	c.w.WriteHeader(code)
+	_, err := io.Copy(c.w, r)
+	return err
+}
+
+// File sends the contents of the file at the given path.
+//
+// The file is served with http.ServeContent, so range requests, and
+// conditional requests using If-Modified-Since, are supported. If the file
+// doesn't exist, a 404 Not Found HTTPError is returned.
+func (c *context) File(path string) error {
+	return c.serveFile(path, "")
+}
+
+// serveFile sends the file at the given path, as File does. If the given
+// Content-Disposition isn't empty, it's only set once the file has been
+// opened, so that an error response is never sent as a download.
+func (c *context) serveFile(path, disposition string) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &HTTPError{Code: http.StatusNotFound, Err: err}
+		}
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return NewHTTPError(http.StatusNotFound)
+	}
+
+	if disposition != "" {
+		c.w.Header().Set("Content-Disposition", disposition)
+	}
+	http.ServeContent(c.w, c.r, fi.Name(), fi.ModTime(), f)
+	return nil
+}
+
+// Attachment sends the file at the given path as a download, which the
+// browser saves with the given filename.
+func (c *context) Attachment(path, filename string) error {
+	return c.serveFile(path, contentDisposition("attachment", filename))
+}
+
+// Inline sends the file at the given path to be displayed by the browser,
+// using the given filename if the user saves it.
+func (c *context) Inline(path, filename string) error {
+	return c.serveFile(path, contentDisposition("inline", filename))
+}
+
+// contentDisposition returns the value of a Content-Disposition header with
+// the given type and filename.
+//
+// As described in RFC 6266, the filename parameter contains an ASCII only
+// fallback for older clients, and if the filename contains any other
+// characters, the filename* parameter contains it encoded in UTF-8.
+func contentDisposition(dispositionType, filename string) string {
+	fallback := make([]byte, 0, len(filename))
+	ascii := true
+	for _, r := range filename {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			fallback = append(fallback, '_')
+			ascii = false
+			continue
+		}
+		fallback = append(fallback, byte(r))
+	}
+
+	disposition := dispositionType + `; filename="` + string(fallback) + `"`
+	if ascii {
+		return disposition
+	}
+
+	// Percent encode everything other than the characters allowed unencoded
+	// by RFC 5987's attr-char.
+	var encoded strings.Builder
+	for _, b := range []byte(filename) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&encoded, "%%%02X", b)
+	}
+
+	return disposition + "; filename*=UTF-8''" + encoded.String()
+}
+
+// isAttrChar returns true if the given byte can appear unencoded in an
+// extended parameter value.
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// NoContent sends a 204 No Content HTTP response. It returns an error if a
+// response has already been written.
 func (c *context) NoContent() error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
 	c.w.WriteHeader(204)
 	return nil
 }
 
 // Render renders an HTML template.
+//
+// If the request was made by htmx or a Turbo Frame, only the template's
+// "main" block is rendered, without the layout, unless another Block is
+// given.
 func (c *context) Render(name string, data interface{}, opts ...RenderOption) error {
-	return c.render.HTML(c.w, c.r, name, data, opts...)
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
+	opt := newRenderOption(opts)
+	if opt.Block == "" && c.isFragment() {
+		opt.Block = fragmentBlock
+	}
+
+	// The response differs depending on whether the request was for a
+	// fragment, so caches must treat them as separate responses.
+	c.w.Header().Add("Vary", "HX-Request, Turbo-Frame")
+
+	return c.render.html(c.w, c.r, name, c.templateData(data), c.funcs(), opt)
+}
+
+// RenderToString renders an HTML template to a string, with access to the
+// same request specific template funcs as Render.
+func (c *context) RenderToString(name string, data interface{}, opts ...RenderOption) (string, error) {
+	buf, err := c.render.execute(c.r, name, c.templateData(data), c.funcs(), newRenderOption(opts))
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// funcs returns the template funcs that rely on request specific data.
+func (c *context) funcs() template.FuncMap {
+	return template.FuncMap{
+		// Add a default template method for accessing all of the flash
+		// messages in order to make it easier to render them from any
+		// template.
+		"flashes": c.flashes,
+
+		// Expose the validation errors and previous input of a form that
+		// failed validation.
+		"field_error": c.fieldError,
+		"old":         c.old,
+
+		// Expose the app's globals and the context's values, for templates
+		// that are rendered with data that they can't be merged into.
+		"locals": c.locals,
+	}
 }
 
-// Redirect redirects the to the given url. It will never return an error.
+// Redirect redirects the to the given url. It returns an error if a response
+// has already been written.
 func (c *context) Redirect(url string) error {
+	if c.w.Committed() {
+		return ErrResponseCommitted
+	}
+
 	code := http.StatusFound
 
 	if c.r.Method == http.MethodPost ||
@@ -1,8 +1,10 @@
 package seatbelt_test
 
 import (
+	"errors"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/bentranter/go-seatbelt"
@@ -15,7 +17,7 @@ func TestContextResponse(t *testing.T) {
 	c := seatbelt.NewTestContext(w, r, nil)
 
 	fn := func(c seatbelt.Context) error {
-		if !reflect.DeepEqual(w, c.Response()) {
+		if !reflect.DeepEqual(w, c.Response().Unwrap()) {
 			t.Fatalf("expected %+v and %+v to be equal", r, w)
 		}
 
@@ -67,3 +69,158 @@ func TestContextNoContent(t *testing.T) {
 		t.Fatalf("expected HTTP 204 but got %d", status)
 	}
 }
+
+func TestContextResponseCommitted(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	c := seatbelt.NewTestContext(w, r, nil)
+
+	if err := c.String(201, "created"); err != nil {
+		t.Fatalf("%+v sending string", err)
+	}
+	if err := c.NoContent(); err != seatbelt.ErrResponseCommitted {
+		t.Fatalf("expected ErrResponseCommitted but got %v", err)
+	}
+
+	if status := c.ResponseRecorder.Code; status != 201 {
+		t.Fatalf("expected HTTP 201 but got %d", status)
+	}
+	if status := c.Response().Status(); status != 201 {
+		t.Fatalf("expected tracked status 201 but got %d", status)
+	}
+	if size := c.Response().Size(); size != len("created") {
+		t.Fatalf("expected tracked size %d but got %d", len("created"), size)
+	}
+}
+
+func TestContextXML(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	c := seatbelt.NewTestContext(w, r, nil)
+
+	type item struct {
+		XMLName struct{} `xml:"item"`
+		Name    string   `xml:"name"`
+	}
+	if err := c.XML(200, item{Name: "Widget"}); err != nil {
+		t.Fatalf("%+v sending xml", err)
+	}
+
+	if ct := c.ResponseRecorder.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Fatalf("expected content type application/xml but got %s", ct)
+	}
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<item><name>Widget</name></item>`
+	if body := c.ResponseRecorder.Body.String(); body != expected {
+		t.Fatalf("expected body %q but got %q", expected, body)
+	}
+}
+
+func TestContextBlobAndStream(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	c := seatbelt.NewTestContext(w, r, nil)
+
+	if err := c.Blob(201, "image/png", []byte("png")); err != nil {
+		t.Fatalf("%+v sending blob", err)
+	}
+	if status := c.ResponseRecorder.Code; status != 201 {
+		t.Fatalf("expected HTTP 201 but got %d", status)
+	}
+	if ct := c.ResponseRecorder.Header().Get("Content-Type"); ct != "image/png" {
+		t.Fatalf("expected content type image/png but got %s", ct)
+	}
+
+	w = httptest.NewRecorder()
+	c = seatbelt.NewTestContext(w, r, nil)
+
+	if err := c.Stream(200, "text/csv", strings.NewReader("a,b\n1,2\n")); err != nil {
+		t.Fatalf("%+v sending stream", err)
+	}
+	if body := c.ResponseRecorder.Body.String(); body != "a,b\n1,2\n" {
+		t.Fatalf("expected streamed body but got %q", body)
+	}
+}
+
+func TestContextFile(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Range", "bytes=0-3")
+
+	c := seatbelt.NewTestContext(w, r, nil)
+
+	if err := c.File("testdata/plaintext/plain.txt"); err != nil {
+		t.Fatalf("%+v sending file", err)
+	}
+	if status := c.ResponseRecorder.Code; status != 206 {
+		t.Fatalf("expected HTTP 206 but got %d", status)
+	}
+	if size := c.ResponseRecorder.Body.Len(); size != 4 {
+		t.Fatalf("expected 4 bytes but got %d", size)
+	}
+
+	modTime := c.ResponseRecorder.Header().Get("Last-Modified")
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-Modified-Since", modTime)
+	c = seatbelt.NewTestContext(w, r, nil)
+
+	if err := c.File("testdata/plaintext/plain.txt"); err != nil {
+		t.Fatalf("%+v sending file", err)
+	}
+	if status := c.ResponseRecorder.Code; status != 304 {
+		t.Fatalf("expected HTTP 304 but got %d", status)
+	}
+
+	c = seatbelt.NewTestContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), nil)
+
+	err := c.File("testdata/missing.txt")
+	var httpErr *seatbelt.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != 404 {
+		t.Fatalf("expected a 404 HTTPError but got %v", err)
+	}
+}
+
+func TestContextAttachment(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	c := seatbelt.NewTestContext(w, r, nil)
+
+	if err := c.Attachment("testdata/plaintext/plain.txt", "report.txt"); err != nil {
+		t.Fatalf("%+v sending attachment", err)
+	}
+	expected := `attachment; filename="report.txt"`
+	if cd := c.ResponseRecorder.Header().Get("Content-Disposition"); cd != expected {
+		t.Fatalf("expected Content-Disposition %q but got %q", expected, cd)
+	}
+
+	w = httptest.NewRecorder()
+	c = seatbelt.NewTestContext(w, r, nil)
+
+	if err := c.Inline("testdata/plaintext/plain.txt", `résumé "final".txt`); err != nil {
+		t.Fatalf("%+v sending inline file", err)
+	}
+	expected = `inline; filename="r_sum_ _final_.txt"; filename*=UTF-8''r%C3%A9sum%C3%A9%20%22final%22.txt`
+	if cd := c.ResponseRecorder.Header().Get("Content-Disposition"); cd != expected {
+		t.Fatalf("expected Content-Disposition %q but got %q", expected, cd)
+	}
+
+	// The error page for a missing file must not be sent as a download.
+	app := seatbelt.New()
+	app.Get("/download", func(c seatbelt.Context) error {
+		return c.Attachment("testdata/missing.txt", "report.txt")
+	})
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/download", nil))
+
+	if w.Code != 404 {
+		t.Fatalf("expected HTTP 404 but got %d", w.Code)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != "" {
+		t.Fatalf("expected no Content-Disposition for a missing file but got %q", cd)
+	}
+}
@@ -37,6 +37,10 @@ type Session interface {
 }
 
 func (c *context) Session() Session {
+	if c.testSession != nil {
+		return c.testSession
+	}
+
 	return &session{
 		r:     c.r,
 		w:     c.w,
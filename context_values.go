@@ -0,0 +1,190 @@
+package seatbelt
+
+import (
+	sctx "context"
+	"net/http"
+	"sync"
+)
+
+// A ContextKey is the key that values set on a Seatbelt context are stored
+// with in the request's context.Context.
+//
+// This allows standard library middleware to share values with Seatbelt
+// handlers, ie,
+//
+//	ctx := context.WithValue(r.Context(), seatbelt.ContextKey("current_user"), user)
+//	next.ServeHTTP(w, r.WithContext(ctx))
+//
+// can be read in a Seatbelt handler with c.Get("current_user"). Since the
+// values of a context.Context can't be listed, values stored this way
+// aren't merged into template data. Use SetValue to share a value that
+// templates can use.
+type ContextKey string
+
+// valuesKey is the context key of the contextValues attached to a request.
+type valuesKey struct{}
+
+// contextValues is the context.Context that holds the values set with Set
+// or SetValue. It's attached to a request once, when the application starts
+// serving it, so that setting a value never creates a copy of the request.
+type contextValues struct {
+	sctx.Context
+
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// withValues returns the given request with a contextValues attached to it,
+// along with the contextValues. If it already has one, the request is
+// returned as is.
+func withValues(r *http.Request) (*http.Request, *contextValues) {
+	if cv, ok := r.Context().Value(valuesKey{}).(*contextValues); ok {
+		return r, cv
+	}
+
+	cv := &contextValues{Context: r.Context(), values: make(map[string]interface{})}
+	return r.WithContext(cv), cv
+}
+
+// Value returns the value set with the given ContextKey, or the value of
+// the given key in the parent context.
+func (cv *contextValues) Value(key interface{}) interface{} {
+	switch k := key.(type) {
+	case valuesKey:
+		return cv
+	case ContextKey:
+		cv.mu.RLock()
+		value, ok := cv.values[string(k)]
+		cv.mu.RUnlock()
+		if ok {
+			return value
+		}
+	}
+	return cv.Context.Value(key)
+}
+
+// set saves a value with the given key.
+func (cv *contextValues) set(key string, value interface{}) {
+	cv.mu.Lock()
+	cv.values[key] = value
+	cv.mu.Unlock()
+}
+
+// get returns the value with the given key, or nil if there isn't one.
+func (cv *contextValues) get(key string) interface{} {
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+	return cv.values[key]
+}
+
+// each calls fn with each of the values.
+func (cv *contextValues) each(fn func(key string, value interface{})) {
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+
+	for key, value := range cv.values {
+		fn(key, value)
+	}
+}
+
+// SetValue saves a value on the given request with the given key, so that
+// standard library middleware can share values with Seatbelt handlers, ie,
+//
+//	next.ServeHTTP(w, seatbelt.SetValue(r, "current_user", user))
+//
+// The value can be read in a Seatbelt handler with c.Get("current_user"),
+// and is merged into the data of every template the handler renders, as if
+// it had been set with Set. Requests served by a Seatbelt application are
+// returned as is, since the value is saved on their existing context.
+func SetValue(r *http.Request, key string, value interface{}) *http.Request {
+	r, cv := withValues(r)
+	cv.set(key, value)
+	return r
+}
+
+// Set saves a value on the context with the given key, so that it can be
+// used later in the request, ie, by the handler after a middleware has run.
+//
+// Values are visible to anything with access to the request's
+// context.Context, under a ContextKey, and are shared with every copy of
+// the context made with WithRequest. Values set on the context are merged
+// into the data of every template the context renders.
+func (c *context) Set(key string, value interface{}) {
+	c.values.set(key, value)
+}
+
+// Get returns the value saved on the context with the given key, or nil if
+// there isn't one.
+func (c *context) Get(key string) interface{} {
+	if value := c.r.Context().Value(ContextKey(key)); value != nil {
+		return value
+	}
+
+	// The request may have been replaced with one whose context.Context
+	// isn't derived from the original.
+	return c.values.get(key)
+}
+
+// MustGet returns the value saved on the context with the given key. It
+// panics if there isn't one.
+func (c *context) MustGet(key string) interface{} {
+	value := c.Get(key)
+	if value == nil {
+		panic("seatbelt: no value set on context for key " + key)
+	}
+	return value
+}
+
+// WithRequest returns a copy of the context with its request replaced by
+// the given request. This allows middleware to pass a request with an
+// enriched context.Context to the next handler, ie,
+//
+//	return next(c.WithRequest(r.WithContext(ctx)))
+//
+// The copy shares its values with the original context, so a value set on
+// either is visible to both.
+func (c *context) WithRequest(r *http.Request) Context {
+	cc := *c
+	cc.r = r
+	return &cc
+}
+
+// locals returns the app's global template data merged with the values set
+// on the context. Values set on the context take precedence.
+func (c *context) locals() map[string]interface{} {
+	locals := make(map[string]interface{})
+
+	if c.app != nil {
+		for key, value := range c.app.globals {
+			locals[key] = value
+		}
+	}
+	c.values.each(func(key string, value interface{}) {
+		locals[key] = value
+	})
+
+	return locals
+}
+
+// templateData returns the data to render a template with.
+//
+// If the data is a map[string]interface{}, or nil, the locals are merged
+// into it, with the given data taking precedence. Any other data, such as a
+// struct, is returned as is, and the locals are available through the
+// `locals` template func instead.
+func (c *context) templateData(data interface{}) interface{} {
+	if data == nil {
+		return c.locals()
+	}
+
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return data
+	}
+
+	merged := c.locals()
+	for key, value := range m {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,163 @@
+package seatbelt_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestContextTemplateLocals(t *testing.T) {
+	t.Parallel()
+
+	dir := writeTemplates(t, map[string]string{
+		"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+		"home/map.html":            `{{ define "main" }}{{ .app_name }} {{ .current_user }} {{ .title }} {{ .tenant }}{{ end }}`,
+		"home/struct.html":         `{{ define "main" }}{{ (locals).app_name }} {{ (locals).current_user }} {{ .Title }} {{ (locals).tenant }}{{ end }}`,
+	})
+
+	app := seatbelt.New(seatbelt.Option{TemplateDir: dir})
+	app.SetGlobal("app_name", "Seatbelt")
+	app.SetGlobal("title", "Default")
+	app.UseStd(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, seatbelt.SetValue(r, "tenant", "acme"))
+		})
+	})
+	app.Use(func(next func(seatbelt.Context) error) func(seatbelt.Context) error {
+		return func(c seatbelt.Context) error {
+			c.Set("current_user", "ben")
+			return next(c)
+		}
+	})
+
+	app.Get("/map", func(c seatbelt.Context) error {
+		return c.Render("home/map", map[string]interface{}{"title": "Map"})
+	})
+	app.Get("/nil", func(c seatbelt.Context) error {
+		return c.Render("home/map", nil)
+	})
+	app.Get("/struct", func(c seatbelt.Context) error {
+		return c.Render("home/struct", struct{ Title string }{"Struct"})
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	cases := map[string]string{
+		"/map":    "Seatbelt ben Map acme",
+		"/nil":    "Seatbelt ben Default acme",
+		"/struct": "Seatbelt ben Struct acme",
+	}
+
+	for path, expected := range cases {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("%+v executing http request", err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%+v reading body", err)
+		}
+
+		if string(body) != expected {
+			t.Fatalf("expected %s for %s but got %s", expected, path, body)
+		}
+	}
+}
+
+func TestContextValues(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New()
+
+	// A standard library middleware sets a value that the Seatbelt handler
+	// reads.
+	app.UseStd(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), seatbelt.ContextKey("tenant"), "acme")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+
+	// A Seatbelt middleware sets a value on the context, and replaces the
+	// request.
+	app.Use(func(next func(seatbelt.Context) error) func(seatbelt.Context) error {
+		return func(c seatbelt.Context) error {
+			c.Set("current_user", "ben")
+
+			r := c.Request().Clone(c.Request().Context())
+			r.Header.Set("X-Enriched", "true")
+			return next(c.WithRequest(r))
+		}
+	})
+
+	app.Get("/", func(c seatbelt.Context) error {
+		user := c.MustGet("current_user").(string)
+		tenant := c.Get("tenant").(string)
+
+		// Values are visible to anything with access to the request's
+		// context.Context.
+		fromStd := c.Request().Context().Value(seatbelt.ContextKey("current_user"))
+
+		return c.String(200, user+" "+tenant+" "+fromStd.(string)+" "+c.Request().Header.Get("X-Enriched"))
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%+v reading body", err)
+	}
+
+	if expected := "ben acme ben true"; string(body) != expected {
+		t.Fatalf("expected %s but got %s", expected, body)
+	}
+}
+
+func TestContextSetKeepsRequest(t *testing.T) {
+	t.Parallel()
+
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	r := c.Request()
+
+	c.Set("current_user", "ben")
+	if c.Request() != r || c.Req != r {
+		t.Fatalf("expected Set to keep the request")
+	}
+	if user := c.Req.Context().Value(seatbelt.ContextKey("current_user")); user != "ben" {
+		t.Fatalf("expected ben in the request's context but got %v", user)
+	}
+
+	// Values set on a copy of the context are visible to the original.
+	cc := c.WithRequest(r.Clone(context.Background()))
+	cc.Set("tenant", "acme")
+	if tenant := c.Get("tenant"); tenant != "acme" {
+		t.Fatalf("expected acme from the original context but got %v", tenant)
+	}
+	if user := cc.Get("current_user"); user != "ben" {
+		t.Fatalf("expected ben from the copy of the context but got %v", user)
+	}
+}
+
+func TestContextMustGetPanics(t *testing.T) {
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected MustGet to panic for a missing key")
+		}
+	}()
+
+	c.MustGet("missing")
+}
@@ -0,0 +1,58 @@
+package seatbelt
+
+import (
+	"errors"
+	"net/http"
+)
+
+// An HTTPError is an error that can be returned from a handler in order to
+// respond with a specific HTTP status code, rather than the default 500
+// Internal Server Error.
+type HTTPError struct {
+	Code    int    // The HTTP status code.
+	Message string // The message shown to the user. Defaults to the status text.
+	Err     error  // The underlying error, if there is one.
+}
+
+// NewHTTPError returns a new HTTPError with the given status code, and an
+// optional message.
+func NewHTTPError(code int, message ...string) *HTTPError {
+	e := &HTTPError{Code: code}
+	if len(message) > 0 {
+		e.Message = message[0]
+	}
+	return e
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Code)
+}
+
+// Unwrap returns the underlying error, if there is one.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ErrTimeout is wrapped by the HTTPError passed to the error handler when a
+// handler doesn't return before its timeout, ie,
+//
+//	if errors.Is(err, seatbelt.ErrTimeout) {
+//		// ...
+//	}
+var ErrTimeout = errors.New("the request timed out")
+
+// tooLargeError returns the HTTPError for a request that's too large,
+// wrapping the given error, ie, ErrUploadTooLarge or ErrBodyTooLarge. A new
+// one is returned each time, so that one request's error can't be changed by
+// another.
+func tooLargeError(err error) error {
+	return &HTTPError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: err.Error(),
+		Err:     err,
+	}
+}
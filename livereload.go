@@ -0,0 +1,125 @@
+package seatbelt
+
+import (
+	"net/http"
+	"sync"
+)
+
+// liveReloadPath is the path of the event stream that browsers listen to in
+// order to know when to reload the page.
+const liveReloadPath = "/_seatbelt/live-reload"
+
+// liveReloadScript is injected into every HTML page rendered while live
+// reload is enabled. If the connection drops, ie, when the server restarts,
+// the page is reloaded as soon as it reconnects.
+const liveReloadScript = `<script>(function(){var s=new EventSource("` + liveReloadPath + `"),e=false;s.addEventListener("reload",function(){location.reload()});s.onerror=function(){e=true};s.onopen=function(){if(e)location.reload()}})();</script>`
+
+// A liveReloader tells every connected browser to reload the page whenever
+// the templates or any of the directories it watches change.
+type liveReloader struct {
+	mu      sync.Mutex
+	watcher *watcher
+	clients map[chan struct{}]struct{}
+}
+
+// newLiveReloader returns a new liveReloader that reloads the page whenever
+// the given renderer's templates are reloaded.
+func newLiveReloader(render *Renderer) *liveReloader {
+	lr := &liveReloader{
+		clients: make(map[chan struct{}]struct{}),
+	}
+
+	// Wait for the templates to be parsed before reloading the page, rather
+	// than watching the template directory separately, so that the browser
+	// never requests the page before the change has been picked up.
+	render.mu.Lock()
+	render.onReload = lr.reload
+	render.liveReload = true
+	render.mu.Unlock()
+
+	return lr
+}
+
+// watch reloads the page whenever the contents of the given directory
+// change. Nothing is polled until the first directory is watched.
+func (lr *liveReloader) watch(dir string) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	if lr.watcher == nil {
+		lr.watcher = newWatcher(lr.reload, dir)
+		return
+	}
+	lr.watcher.add(dir)
+}
+
+// close stops watching directories for changes.
+func (lr *liveReloader) close() {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	if lr.watcher != nil {
+		lr.watcher.close()
+	}
+}
+
+// reload tells every connected browser to reload the page.
+func (lr *liveReloader) reload() {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	for client := range lr.clients {
+		// Never block on a slow client, since a single pending reload is as
+		// good as many.
+		select {
+		case client <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// middleware serves the live reload event stream.
+func (lr *liveReloader) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != liveReloadPath || r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		client := make(chan struct{}, 1)
+
+		lr.mu.Lock()
+		lr.clients[client] = struct{}{}
+		lr.mu.Unlock()
+
+		defer func() {
+			lr.mu.Lock()
+			delete(lr.clients, client)
+			lr.mu.Unlock()
+		}()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case <-client:
+				if _, err := w.Write([]byte("event: reload\ndata: \n\n")); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
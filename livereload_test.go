@@ -0,0 +1,121 @@
+package seatbelt_test
+
+import (
+	"bufio"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestLiveReload(t *testing.T) {
+	t.Parallel()
+
+	dir := writeTemplates(t, map[string]string{
+		"layouts/application.html": `<body>{{ block "main" . }}{{ end }}</body>`,
+		"home/index.html":          `{{ define "main" }}home{{ end }}`,
+	})
+	static := t.TempDir()
+
+	app := seatbelt.New(seatbelt.Option{
+		TemplateDir: dir,
+		Reload:      true,
+		LiveReload:  true,
+	})
+	app.Get("/", func(c seatbelt.Context) error {
+		return c.Render("home/index", nil)
+	})
+	app.FileServer("/static", static)
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	page, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%+v reading body", err)
+	}
+	if !strings.Contains(string(page), "EventSource") || !strings.HasSuffix(string(page), "</script></body>") {
+		t.Fatalf("expected live reload script before closing body tag in %s", page)
+	}
+
+	// expectReload changes a file and waits for the reload event.
+	expectReload := func(t *testing.T, path string) {
+		t.Helper()
+
+		resp, err := http.Get(srv.URL + "/_seatbelt/live-reload")
+		if err != nil {
+			t.Fatalf("%+v connecting to event stream", err)
+		}
+		defer resp.Body.Close()
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected text/event-stream but got %s", ct)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(`{{ define "main" }}changed{{ end }}`), 0644); err != nil {
+			t.Fatalf("%+v writing file", err)
+		}
+
+		events := make(chan string)
+		go func() {
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				events <- scanner.Text()
+			}
+			close(events)
+		}()
+
+		select {
+		case event := <-events:
+			if event != "event: reload" {
+				t.Fatalf("expected reload event but got %s", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for reload event")
+		}
+	}
+
+	t.Run("template change", func(t *testing.T) {
+		expectReload(t, filepath.Join(dir, "home", "index.html"))
+	})
+
+	t.Run("static file change", func(t *testing.T) {
+		expectReload(t, filepath.Join(static, "app.css"))
+	})
+}
+
+func TestLiveReloadDisabledOutsideDevelopment(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New(seatbelt.Option{
+		TemplateDir: "testdata",
+		LiveReload:  true,
+		Funcs: template.FuncMap{
+			"lower": strings.ToLower,
+		},
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/_seatbelt/live-reload")
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 but got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,341 @@
+package seatbelt
+
+import (
+	"html/template"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultPerPage is the number of items on each page, unless it's
+	// changed with the PerPage option.
+	defaultPerPage = 20
+
+	// defaultMaxPerPage is the largest number of items on each page that
+	// can be requested, unless it's changed with the MaxPerPage option.
+	defaultMaxPerPage = 100
+)
+
+// A PaginationOption configures how Paginate reads the page from the query
+// params.
+type PaginationOption struct {
+	// PerPage is the number of items on each page when the per_page query
+	// param isn't sent. The default is 20.
+	PerPage int
+
+	// MaxPerPage is the largest number of items on each page that can be
+	// requested with the per_page query param. The default is 100.
+	MaxPerPage int
+
+	// Cursors, if true, paginates with the cursor query param, rather than
+	// page numbers. The handler sets the NextCursor and PrevCursor of the
+	// Pagination after loading the page.
+	Cursors bool
+}
+
+// A Pagination describes the page of a list requested with the page,
+// per_page, and cursor query params.
+type Pagination struct {
+	// Page is the current page number, starting at 1.
+	Page int `json:"page,omitempty"`
+
+	// PerPage is the number of items on each page.
+	PerPage int `json:"per_page"`
+
+	// Total is the total number of items, which the handler sets after
+	// counting them, so that the number of pages is known.
+	Total int `json:"total,omitempty"`
+
+	// Cursor is the cursor of the current page, when paginating with
+	// cursors. It's empty for the first page.
+	Cursor string `json:"cursor,omitempty"`
+
+	// NextCursor and PrevCursor are the cursors of the next and previous
+	// pages, which the handler sets when paginating with cursors. If
+	// either is empty, there is no such page.
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+
+	cursors bool
+	url     *url.URL
+}
+
+// Paginate returns the Pagination for the current request, from its page,
+// per_page, and cursor query params, ie,
+//
+//	p, err := c.Paginate()
+//	if err != nil {
+//		return err
+//	}
+//	products, total, err := store.ListProducts(p.Offset(), p.Limit())
+//	if err != nil {
+//		return err
+//	}
+//	p.Total = total
+//
+// The page and per_page are limited to sensible values, so a page below 1 is
+// the first page, and a per_page above the MaxPerPage option is reduced to
+// it. If either isn't an integer, an HTTPError with the status 400 Bad
+// Request is returned.
+func (c *context) Paginate(opts ...PaginationOption) (*Pagination, error) {
+	opt := PaginationOption{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.PerPage <= 0 {
+		opt.PerPage = defaultPerPage
+	}
+	if opt.MaxPerPage <= 0 {
+		opt.MaxPerPage = defaultMaxPerPage
+	}
+
+	perPage, err := c.QueryParamInt("per_page", opt.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	if perPage < 1 {
+		perPage = opt.PerPage
+	}
+	if perPage > opt.MaxPerPage {
+		perPage = opt.MaxPerPage
+	}
+
+	// Link to the same format that was requested with the path's
+	// extension, even though it was removed before routing.
+	u := *c.r.URL
+	if format := c.pathFormat(); format != "" {
+		u.Path += "." + format
+		u.RawPath = ""
+	}
+
+	p := &Pagination{
+		Page:    1,
+		PerPage: perPage,
+		cursors: opt.Cursors,
+		url:     &u,
+	}
+
+	if opt.Cursors {
+		p.Cursor = c.QueryParam("cursor")
+		return p, nil
+	}
+
+	page, err := c.QueryParamInt("page", 1)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	// Keep the offset from overflowing.
+	if max := math.MaxInt32 / perPage; page > max {
+		page = max
+	}
+	p.Page = page
+
+	return p, nil
+}
+
+// Offset returns the number of items before the current page.
+func (p *Pagination) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+// Limit returns the number of items on the current page.
+func (p *Pagination) Limit() int {
+	return p.PerPage
+}
+
+// TotalPages returns the number of pages, or zero if the Total isn't known.
+func (p *Pagination) TotalPages() int {
+	if p.Total <= 0 || p.PerPage <= 0 {
+		return 0
+	}
+	return (p.Total + p.PerPage - 1) / p.PerPage
+}
+
+// HasPrev returns true if there's a page before the current page.
+func (p *Pagination) HasPrev() bool {
+	if p.cursors {
+		return p.PrevCursor != ""
+	}
+	return p.Page > 1
+}
+
+// HasNext returns true if there's a page after the current page.
+func (p *Pagination) HasNext() bool {
+	if p.cursors {
+		return p.NextCursor != ""
+	}
+	return p.Page < p.TotalPages()
+}
+
+// PageURL returns the URL of the page with the given number, keeping the
+// request's other query params.
+func (p *Pagination) PageURL(page int) string {
+	return p.link("page", strconv.Itoa(page))
+}
+
+// PrevURL returns the URL of the previous page, or an empty string if
+// there isn't one.
+func (p *Pagination) PrevURL() string {
+	switch {
+	case !p.HasPrev():
+		return ""
+	case p.cursors:
+		return p.link("cursor", p.PrevCursor)
+	}
+	return p.PageURL(p.Page - 1)
+}
+
+// NextURL returns the URL of the next page, or an empty string if there
+// isn't one.
+func (p *Pagination) NextURL() string {
+	switch {
+	case !p.HasNext():
+		return ""
+	case p.cursors:
+		return p.link("cursor", p.NextCursor)
+	}
+	return p.PageURL(p.Page + 1)
+}
+
+// FirstURL returns the URL of the first page.
+func (p *Pagination) FirstURL() string {
+	if p.cursors {
+		return p.link("cursor", "")
+	}
+	return p.PageURL(1)
+}
+
+// LastURL returns the URL of the last page, or an empty string if the number
+// of pages isn't known, which is always the case when paginating with
+// cursors.
+func (p *Pagination) LastURL() string {
+	if p.cursors || p.TotalPages() == 0 {
+		return ""
+	}
+	return p.PageURL(p.TotalPages())
+}
+
+// LinkHeader returns the value of a Link header, as described in RFC 8288,
+// with the URLs of the first, previous, next, and last pages, for JSON
+// responses, ie,
+//
+//	c.Response().Header().Set("Link", p.LinkHeader())
+//	return c.JSON(http.StatusOK, products)
+func (p *Pagination) LinkHeader() string {
+	var links []string
+	for _, link := range []struct{ rel, url string }{
+		{rel: "first", url: p.FirstURL()},
+		{rel: "prev", url: p.PrevURL()},
+		{rel: "next", url: p.NextURL()},
+		{rel: "last", url: p.LastURL()},
+	} {
+		if link.url != "" {
+			links = append(links, "<"+link.url+`>; rel="`+link.rel+`"`)
+		}
+	}
+	return strings.Join(links, ", ")
+}
+
+// link returns the URL of the current request, relative to its host, with
+// the given query param set, or removed if the value is empty.
+func (p *Pagination) link(key, value string) string {
+	u := url.URL{Path: "/"}
+	if p.url != nil {
+		u = *p.url
+	}
+	u.Scheme, u.Host, u.User, u.Fragment = "", "", nil, ""
+
+	q := u.Query()
+	if value == "" {
+		q.Del(key)
+	} else {
+		q.Set(key, value)
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// pageWindow returns the page numbers to link to, which are the first and
+// last pages, and the pages around the current page. A zero marks a gap
+// between pages.
+func pageWindow(current, total int) []int {
+	const radius = 2
+
+	var pages []int
+	add := func(page int) {
+		if page < 1 || page > total {
+			return
+		}
+		if n := len(pages); n > 0 {
+			if pages[n-1] >= page {
+				return
+			}
+			if pages[n-1] < page-1 {
+				pages = append(pages, 0)
+			}
+		}
+		pages = append(pages, page)
+	}
+
+	add(1)
+	for page := current - radius; page <= current+radius; page++ {
+		add(page)
+	}
+	add(total)
+
+	return pages
+}
+
+// paginate renders the links to the pages of the given Pagination, for the
+// paginate template func, ie,
+//
+//	{{ paginate .Pagination }}
+//
+// The links keep the request's other query params, so that filters and
+// sorting are preserved. If there's only one page, nothing is rendered.
+func paginate(p *Pagination) template.HTML {
+	if p == nil || (!p.HasPrev() && !p.HasNext()) {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(`<nav class="pagination" aria-label="Pagination"><ul>`)
+
+	item := func(href, label, text, attrs string) {
+		if href == "" {
+			b.WriteString(`<li><span aria-disabled="true">` + text + `</span></li>`)
+			return
+		}
+		b.WriteString(`<li><a href="` + template.HTMLEscapeString(href) + `" aria-label="` + label + `"` + attrs + `>` + text + `</a></li>`)
+	}
+
+	item(p.PrevURL(), "Previous page", "Previous", ` rel="prev"`)
+
+	if !p.cursors {
+		for _, page := range pageWindow(p.Page, p.TotalPages()) {
+			if page == 0 {
+				b.WriteString(`<li><span aria-hidden="true">&hellip;</span></li>`)
+				continue
+			}
+
+			n := strconv.Itoa(page)
+			attrs := ""
+			if page == p.Page {
+				attrs = ` aria-current="page"`
+			}
+			item(p.PageURL(page), "Page "+n, n, attrs)
+		}
+	}
+
+	item(p.NextURL(), "Next page", "Next", ` rel="next"`)
+
+	b.WriteString(`</ul></nav>`)
+	return template.HTML(b.String())
+}
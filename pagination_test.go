@@ -0,0 +1,170 @@
+package seatbelt_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestContextPaginate(t *testing.T) {
+	cases := []struct {
+		query   string
+		page    int
+		perPage int
+		offset  int
+	}{
+		{query: "", page: 1, perPage: 20, offset: 0},
+		{query: "page=3", page: 3, perPage: 20, offset: 40},
+		{query: "page=2&per_page=10", page: 2, perPage: 10, offset: 10},
+		{query: "page=-4&per_page=0", page: 1, perPage: 20, offset: 0},
+		{query: "per_page=5000", page: 1, perPage: 100, offset: 0},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/products?"+tc.query, nil)
+		c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+		p, err := c.Paginate()
+		if err != nil {
+			t.Fatalf("%+v paginating %s", err, tc.query)
+		}
+		if p.Page != tc.page || p.PerPage != tc.perPage || p.Offset() != tc.offset || p.Limit() != tc.perPage {
+			t.Fatalf("%s: expected page %d of %d at offset %d but got %+v", tc.query, tc.page, tc.perPage, tc.offset, p)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/products?page=two", nil)
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+	_, err := c.Paginate()
+	if herr, ok := err.(*seatbelt.HTTPError); !ok || herr.Code != http.StatusBadRequest {
+		t.Fatalf("expected HTTP 400 error but got %v", err)
+	}
+}
+
+func TestPaginationLinks(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/products?q=shoes&page=2&per_page=10", nil)
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+	p, err := c.Paginate()
+	if err != nil {
+		t.Fatalf("%+v paginating", err)
+	}
+	p.Total = 45
+
+	if p.TotalPages() != 5 || !p.HasPrev() || !p.HasNext() {
+		t.Fatalf("expected 5 pages with a previous and next page but got %+v", p)
+	}
+	if expected := "/products?page=3&per_page=10&q=shoes"; p.NextURL() != expected {
+		t.Fatalf("expected %s but got %s", expected, p.NextURL())
+	}
+
+	expected := `</products?page=1&per_page=10&q=shoes>; rel="first", ` +
+		`</products?page=1&per_page=10&q=shoes>; rel="prev", ` +
+		`</products?page=3&per_page=10&q=shoes>; rel="next", ` +
+		`</products?page=5&per_page=10&q=shoes>; rel="last"`
+	if p.LinkHeader() != expected {
+		t.Fatalf("expected %s but got %s", expected, p.LinkHeader())
+	}
+
+	p.Page = 5
+	if p.HasNext() || p.NextURL() != "" {
+		t.Fatalf("expected no next page on the last page")
+	}
+}
+
+func TestPaginationCursors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events?cursor=abc&type=click", nil)
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+	p, err := c.Paginate(seatbelt.PaginationOption{PerPage: 50, Cursors: true})
+	if err != nil {
+		t.Fatalf("%+v paginating", err)
+	}
+	if p.Cursor != "abc" || p.PerPage != 50 {
+		t.Fatalf("expected cursor abc with 50 per page but got %+v", p)
+	}
+	if p.HasNext() || p.HasPrev() {
+		t.Fatalf("expected no pages until the cursors are set")
+	}
+
+	p.NextCursor = "def"
+	expected := `</events?type=click>; rel="first", </events?cursor=def&type=click>; rel="next"`
+	if p.LinkHeader() != expected {
+		t.Fatalf("expected %s but got %s", expected, p.LinkHeader())
+	}
+}
+
+func TestPaginateTemplateFunc(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New(seatbelt.Option{TemplateDir: writeTemplates(t, map[string]string{
+		"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+		"products/index.html":      `{{ define "main" }}{{ paginate . }}{{ end }}`,
+	})})
+	app.Get("/products", func(c seatbelt.Context) error {
+		p, err := c.Paginate()
+		if err != nil {
+			return err
+		}
+		p.Total = 200
+		if c.QueryParam("total") != "" {
+			p.Total, _ = c.QueryParamInt("total", 0)
+		}
+		return c.Render("products/index", p)
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products?page=6&q=a+b", nil))
+	body := w.Body.String()
+
+	for _, expected := range []string{
+		`<nav class="pagination" aria-label="Pagination">`,
+		`<a href="/products?page=5&amp;q=a+b" aria-label="Previous page" rel="prev">Previous</a>`,
+		`<a href="/products?page=1&amp;q=a+b" aria-label="Page 1">1</a>`,
+		`<a href="/products?page=6&amp;q=a+b" aria-label="Page 6" aria-current="page">6</a>`,
+		`<a href="/products?page=10&amp;q=a+b" aria-label="Page 10">10</a>`,
+		`<span aria-hidden="true">&hellip;</span>`,
+		`rel="next">Next</a>`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("expected %s in %s", expected, body)
+		}
+	}
+	if strings.Contains(body, `aria-label="Page 2"`) || strings.Contains(body, `aria-label="Page 9"`) {
+		t.Fatalf("expected pages far from the current page to be elided but got %s", body)
+	}
+
+	// A single page doesn't need any links.
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products?per_page=100&page=1&total=1", nil))
+	if strings.Contains(w.Body.String(), "<nav") {
+		t.Fatalf("expected no links for a single page but got %s", w.Body.String())
+	}
+}
+
+func TestPaginationLinksKeepFormat(t *testing.T) {
+	var links string
+	app := seatbelt.New()
+	app.Get("/products", func(c seatbelt.Context) error {
+		p, err := c.Paginate()
+		if err != nil {
+			return err
+		}
+		p.Total = 45
+		links = p.LinkHeader()
+		return c.NoContent()
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products.json?page=2", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected HTTP 204 but got %d", w.Code)
+	}
+
+	if expected := `</products.json?page=3>; rel="next"`; !strings.Contains(links, expected) {
+		t.Fatalf("expected %s in %s", expected, links)
+	}
+}
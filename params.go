@@ -0,0 +1,149 @@
+package seatbelt
+
+import (
+	"mime/multipart"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// setParam sets the given values in the given params, using the bracket
+// notation of the given key to build nested maps, ie, the key
+// items[0][name] sets params["items"]["0"]["name"]. A key ending in [],
+// ie, tags[], is always set to a slice, even when it only has one value.
+func setParam(params map[string]interface{}, key string, values []string) {
+	segments, forceSlice := parseParamKey(key)
+	node, last := paramNode(params, segments)
+
+	switch {
+	case forceSlice || len(values) > 1:
+		node[last] = append([]string(nil), values...)
+	case len(values) == 1:
+		node[last] = values[0]
+	}
+}
+
+// setFiles sets the given uploaded files in the given params, in the same
+// way as setParam.
+func setFiles(params map[string]interface{}, key string, files []*multipart.FileHeader) {
+	segments, forceSlice := parseParamKey(key)
+	node, last := paramNode(params, segments)
+
+	switch {
+	case forceSlice || len(files) > 1:
+		node[last] = append([]*multipart.FileHeader(nil), files...)
+	case len(files) == 1:
+		node[last] = files[0]
+	}
+}
+
+// paramNode returns the nested map that holds the param with the given
+// segments, creating it if necessary, along with the param's key within it.
+func paramNode(params map[string]interface{}, segments []string) (map[string]interface{}, string) {
+	node := params
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := node[segment].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			node[segment] = child
+		}
+		node = child
+	}
+	return node, segments[len(segments)-1]
+}
+
+// parseParamKey splits a key in bracket notation into its segments, and
+// returns true if it ends in []. A key that isn't valid bracket notation is
+// returned as its only segment.
+func parseParamKey(key string) ([]string, bool) {
+	i := strings.IndexByte(key, '[')
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return []string{key}, false
+	}
+
+	segments := []string{key[:i]}
+	forceSlice := false
+	rest := key[i:]
+
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return []string{key}, false
+		}
+
+		segment := rest[1:end]
+		rest = rest[end+1:]
+
+		if segment == "" {
+			// Only a trailing [] is meaningful, since there's no way to
+			// know which element a field in the middle belongs to.
+			if rest != "" {
+				return []string{key}, false
+			}
+			forceSlice = true
+			break
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, forceSlice
+}
+
+// collapseIndexes replaces every nested map in the given params whose keys
+// are all indexes, ie, from items[0][name] and items[1][name], with a slice
+// of its values ordered by index, so that it can be decoded into a slice.
+func collapseIndexes(params map[string]interface{}) {
+	for key, value := range params {
+		if m, ok := value.(map[string]interface{}); ok {
+			params[key] = collapse(m)
+		}
+	}
+}
+
+// collapse returns the given map as a slice if its keys are all indexes,
+// after collapsing any maps it contains.
+func collapse(m map[string]interface{}) interface{} {
+	collapseIndexes(m)
+
+	indexes := make([]int, 0, len(m))
+	for key := range m {
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 {
+			return m
+		}
+		indexes = append(indexes, i)
+	}
+	if len(indexes) == 0 {
+		return m
+	}
+	sort.Ints(indexes)
+
+	s := make([]interface{}, len(indexes))
+	for j, i := range indexes {
+		s[j] = m[strconv.Itoa(i)]
+	}
+	return s
+}
+
+// lastValueHook decodes a param with multiple values into a field that
+// only holds one, ie, a string, by using the last value, which is the value
+// of the input that appears last in the form.
+func lastValueHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	switch to.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+		return data, nil
+	}
+
+	switch values := data.(type) {
+	case []string:
+		if len(values) > 0 {
+			return values[len(values)-1], nil
+		}
+	case []*multipart.FileHeader:
+		if len(values) > 0 {
+			return values[len(values)-1], nil
+		}
+	}
+	return data, nil
+}
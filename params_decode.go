@@ -0,0 +1,121 @@
+package seatbelt
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// A DecoderFunc decodes a param's string value into a value of the type it
+// was registered for with RegisterDecoder.
+type DecoderFunc func(value string) (interface{}, error)
+
+// RegisterDecoder registers a func that decodes params into fields of the
+// given type, ie, an enum,
+//
+//	app.RegisterDecoder(reflect.TypeOf(Status(0)), func(value string) (interface{}, error) {
+//		switch value {
+//		case "draft":
+//			return StatusDraft, nil
+//		case "published":
+//			return StatusPublished, nil
+//		}
+//		return nil, errors.New("invalid status " + value)
+//	})
+//
+// Registered decoders take precedence over the built in support for
+// time.Time, time.Duration, and types that implement
+// encoding.TextUnmarshaler.
+//
+// RegisterDecoder is not safe to call concurrently with requests, so
+// decoders should be registered before the application starts.
+func (a *App) RegisterDecoder(t reflect.Type, fn DecoderFunc) {
+	if a.decoders == nil {
+		a.decoders = make(map[reflect.Type]DecoderFunc)
+	}
+	a.decoders[t] = fn
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// timeLayouts are the layouts that params are parsed into a time.Time with,
+// in order. They include the formats sent by HTML date, datetime-local, and
+// time inputs.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"15:04:05",
+	"15:04",
+}
+
+// typeHook returns a decode hook that decodes string params into the types
+// that mapstructure can't decode by itself, using the given registered
+// decoders first.
+func typeHook(decoders map[reflect.Type]DecoderFunc) mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		s, ok := data.(string)
+		if !ok {
+			return data, nil
+		}
+
+		if fn, ok := decoders[to]; ok {
+			return fn(s)
+		}
+
+		switch {
+		case to == timeType:
+			return parseTime(s)
+
+		case to == durationType:
+			if s == "" {
+				return time.Duration(0), nil
+			}
+			return time.ParseDuration(s)
+
+		case to.Kind() != reflect.Ptr && reflect.PtrTo(to).Implements(textUnmarshalerType):
+			v := reflect.New(to)
+			if s != "" {
+				if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+					return nil, err
+				}
+			}
+			return v.Elem().Interface(), nil
+
+		case to.Kind() == reflect.Bool:
+			// Checkboxes are submitted with the value "on" by default.
+			switch strings.ToLower(s) {
+			case "on", "yes":
+				return true, nil
+			case "off", "no":
+				return false, nil
+			}
+		}
+
+		return data, nil
+	}
+}
+
+// parseTime parses the given param into a time.Time using the first
+// matching layout. An empty param is the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a time", s)
+}
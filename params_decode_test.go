@@ -0,0 +1,115 @@
+package seatbelt_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+type status int
+
+const (
+	statusDraft status = iota
+	statusPublished
+)
+
+func TestContextRequestParamsDecoders(t *testing.T) {
+	type event struct {
+		StartsAt  time.Time
+		EndsAt    *time.Time
+		Day       time.Time
+		Published time.Time
+		Timeout   time.Duration
+		IP        net.IP
+		Status    status
+		Public    bool
+	}
+
+	app := seatbelt.New()
+	app.RegisterDecoder(reflect.TypeOf(status(0)), func(value string) (interface{}, error) {
+		switch value {
+		case "draft":
+			return statusDraft, nil
+		case "published":
+			return statusPublished, nil
+		}
+		return nil, errors.New("invalid status " + value)
+	})
+
+	var v event
+	app.Post("/", func(c seatbelt.Context) error {
+		v = event{}
+		if err := c.Params(&v); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.NoContent()
+	})
+
+	form := url.Values{
+		"startsat":  {"2021-06-01T09:30"},
+		"endsat":    {"2021-06-01T17:00:00"},
+		"day":       {"2021-06-01"},
+		"published": {"2021-06-01T09:30:00Z"},
+		"timeout":   {"1m30s"},
+		"ip":        {"192.168.0.1"},
+		"status":    {"published"},
+		"public":    {"on"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected HTTP 204 but got %d: %s", w.Code, w.Body.String())
+	}
+
+	if expected := time.Date(2021, 6, 1, 9, 30, 0, 0, time.UTC); !v.StartsAt.Equal(expected) {
+		t.Fatalf("expected %s but got %s", expected, v.StartsAt)
+	}
+	if v.EndsAt == nil || v.EndsAt.Hour() != 17 {
+		t.Fatalf("expected end time to be decoded but got %v", v.EndsAt)
+	}
+	if v.Day.Year() != 2021 || v.Day.Day() != 1 {
+		t.Fatalf("expected date to be decoded but got %s", v.Day)
+	}
+	if v.Published.IsZero() {
+		t.Fatalf("expected RFC 3339 time to be decoded")
+	}
+	if v.Timeout != 90*time.Second {
+		t.Fatalf("expected 1m30s but got %s", v.Timeout)
+	}
+	if !v.IP.Equal(net.ParseIP("192.168.0.1")) {
+		t.Fatalf("expected 192.168.0.1 but got %s", v.IP)
+	}
+	if v.Status != statusPublished {
+		t.Fatalf("expected published status but got %d", v.Status)
+	}
+	if !v.Public {
+		t.Fatalf("expected checkbox value on to decode to true")
+	}
+
+	for key, value := range map[string]string{
+		"status":   "archived",
+		"startsat": "yesterday",
+		"ip":       "not an ip",
+	} {
+		form := url.Values{key: {value}}
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected HTTP 400 for %s=%s but got %d", key, value, w.Code)
+		}
+	}
+}
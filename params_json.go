@@ -0,0 +1,243 @@
+package seatbelt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// defaultMaxBodySize is the maximum size of a JSON request body, unless
+// it's changed with the MaxBodySize option.
+const defaultMaxBodySize = 1 << 20
+
+// ErrBodyTooLarge is wrapped by the HTTPError returned when a JSON request
+// body is larger than the MaxBodySize option, ie,
+//
+//	if errors.Is(err, seatbelt.ErrBodyTooLarge) {
+//		// ...
+//	}
+var ErrBodyTooLarge = errors.New("the request body is too large")
+
+// isJSON returns true if the request has a JSON body, ie, its content type
+// is application/json, or a type with the +json suffix, with or without
+// parameters like the charset.
+func (c *context) isJSON() bool {
+	if c.r.Method != "POST" && c.r.Method != "PUT" && c.r.Method != "PATCH" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(c.r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// readJSON reads the JSON request body, up to the MaxBodySize option.
+func (c *context) readJSON() ([]byte, error) {
+	max := int64(defaultMaxBodySize)
+	if c.app != nil && c.app.maxBodySize > 0 {
+		max = c.app.maxBodySize
+	}
+	if c.r.ContentLength > max {
+		return nil, tooLargeError(ErrBodyTooLarge)
+	}
+
+	body := &limitedBody{ReadCloser: c.r.Body, remaining: max, err: tooLargeError(ErrBodyTooLarge)}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if body.exceeded {
+		return nil, tooLargeError(ErrBodyTooLarge)
+	}
+	return data, err
+}
+
+// readJSONParams reads the JSON request body, and removes the params that
+// may not be assigned to v from both the body and the given params, which
+// are handled by unpermitted. If v is a pointer to a struct, the filtered
+// body is returned, so that it can be decoded directly into v, otherwise the
+// body is merged into the given params, taking precedence over them.
+func (c *context) readJSONParams(values map[string]interface{}, v interface{}, permit permitTree) ([]byte, error) {
+	data, err := c.readJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(v)
+	if isStructPtr(v) {
+		removed := filterParams(values, t, permit, paramsFieldName, "")
+		filtered, removedJSON, err := filterJSON(data, t, permit)
+		if err != nil {
+			return nil, err
+		}
+		return filtered, c.unpermitted(append(removed, removedJSON...))
+	}
+
+	if len(bytes.TrimSpace(data)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&values); err != nil {
+			return nil, &HTTPError{Code: http.StatusBadRequest, Message: "the request body is not valid JSON", Err: err}
+		}
+	}
+	return nil, c.unpermitted(filterParams(values, t, permit, paramsFieldName, ""))
+}
+
+// disallowUnknownFields returns true if JSON bodies may only contain the
+// fields of the struct they're decoded into.
+func (c *context) disallowUnknownFields() bool {
+	return c.app != nil && c.app.disallowUnknownFields
+}
+
+// decodeJSONStruct decodes the given JSON object into the given pointer to
+// a struct. Each field is decoded directly, rather than through a map, so
+// that numbers keep their precision. A field is named by its json tag, or
+// by its params tag if it doesn't have one, or by its name, which is
+// matched case insensitively.
+//
+// If a field has the wrong type, the returned error is ValidationErrors,
+// describing the problem with each field.
+func decodeJSONStruct(data []byte, v interface{}, disallowUnknown bool) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			return &HTTPError{Code: http.StatusBadRequest, Message: "the request body must be a JSON object", Err: err}
+		}
+		return &HTTPError{Code: http.StatusBadRequest, Message: "the request body is not valid JSON", Err: err}
+	}
+
+	fields := make(map[string]reflect.Value)
+	jsonFields(reflect.ValueOf(v).Elem(), fields)
+
+	errs := make(ValidationErrors)
+	for key, msg := range raw {
+		field, ok := fields[key]
+		if !ok {
+			field, ok = fields[strings.ToLower(key)]
+		}
+		if !ok {
+			if disallowUnknown {
+				errs.Add(key, "is not allowed")
+			}
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(msg))
+		dec.UseNumber()
+		if disallowUnknown {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(field.Addr().Interface()); err != nil {
+			name, message := jsonFieldError(key, err)
+			errs.Add(name, message)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// jsonFields adds each field of the given struct to fields, keyed by both
+// its name and its lowercased name. The fields of embedded structs are
+// added as though they're the parent's own, even when the embedded struct
+// is unexported, as encoding/json does. Fields with a source tag, ie,
+// query:"page", are never assigned from JSON.
+func jsonFields(rv reflect.Value, fields map[string]reflect.Value) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if hasSourceTag(sf) {
+			continue
+		}
+		if sf.PkgPath != "" && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.Split(sf.Tag.Get("params"), ",")[0]
+		}
+		if name == "-" {
+			continue
+		}
+
+		if name == "" && sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			jsonFields(rv.Field(i), fields)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		if _, ok := fields[name]; !ok {
+			fields[name] = rv.Field(i)
+		}
+		if lower := strings.ToLower(name); lower != name {
+			if _, ok := fields[lower]; !ok {
+				fields[lower] = rv.Field(i)
+			}
+		}
+	}
+}
+
+// jsonFieldError returns the name of the field, in bracket notation, and a
+// description of the problem, for an error decoding the JSON field with the
+// given key.
+func jsonFieldError(key string, err error) (string, string) {
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		name := key
+		if e.Field != "" {
+			for _, part := range strings.Split(e.Field, ".") {
+				name += "[" + part + "]"
+			}
+		}
+		return name, "must be " + jsonTypeDescription(e.Type)
+
+	default:
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			return key, "has an unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+		}
+		return key, "is invalid"
+	}
+}
+
+// jsonTypeDescription describes the JSON value expected for the given type.
+func jsonTypeDescription(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a positive integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a valid " + t.String()
+}
@@ -0,0 +1,249 @@
+package seatbelt
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// A ParamsOption changes which params Params and Bind assign.
+type ParamsOption struct {
+	// Permit is a list of the names of the params that may be assigned, in
+	// bracket notation. A name permits every param nested beneath it, ie,
+	// "address" permits address[city], unless the nested params are listed
+	// too, ie, "address[city]" only permits the city. The params nested in
+	// each element of a slice are listed without an index, ie,
+	// "items[name]". If empty, every param is permitted.
+	Permit []string
+}
+
+// Permit returns a ParamsOption that only permits the params with the given
+// names to be assigned, ie,
+//
+//	var product Product
+//	if err := c.Params(&product, seatbelt.Permit("name", "price")); err != nil {
+//		return err
+//	}
+func Permit(names ...string) ParamsOption {
+	return ParamsOption{Permit: names}
+}
+
+// A permitTree holds the names of the permitted params, with the params
+// permitted beneath each. A nil permitTree permits every param.
+type permitTree map[string]permitTree
+
+// newPermitTree returns the permitTree for the given names in bracket
+// notation, or nil if there aren't any.
+func newPermitTree(opts []ParamsOption) permitTree {
+	var tree permitTree
+	for _, opt := range opts {
+		for _, name := range opt.Permit {
+			if tree == nil {
+				tree = make(permitTree)
+			}
+
+			segments, _ := parseParamKey(name)
+			node := tree
+			for i, segment := range segments {
+				child, ok := node[segment]
+				if i == len(segments)-1 {
+					node[segment] = nil
+					break
+				}
+				if ok && child == nil {
+					// Everything beneath the segment is already permitted.
+					break
+				}
+				if !ok {
+					child = make(permitTree)
+					node[segment] = child
+				}
+				node = child
+			}
+		}
+	}
+	return tree
+}
+
+// lookup returns the subtree for the given key, matched case insensitively,
+// as keys are matched to fields, and true if the key is permitted.
+func (t permitTree) lookup(key string) (permitTree, bool) {
+	if t == nil {
+		return nil, true
+	}
+	if child, ok := t[key]; ok {
+		return child, true
+	}
+	for name, child := range t {
+		if strings.EqualFold(name, key) {
+			return child, true
+		}
+	}
+	return nil, false
+}
+
+// paramsFieldName returns the name of the param that's assigned to the
+// given field.
+func paramsFieldName(sf reflect.StructField) string {
+	if name := strings.Split(sf.Tag.Get("params"), ",")[0]; name != "" {
+		return name
+	}
+	return sf.Name
+}
+
+// jsonFieldName returns the name of the JSON field that's assigned to the
+// given field, as jsonFields does.
+func jsonFieldName(sf reflect.StructField) string {
+	if name := strings.Split(sf.Tag.Get("json"), ",")[0]; name != "" {
+		return name
+	}
+	return paramsFieldName(sf)
+}
+
+// findField returns the field of the given struct type that the param with
+// the given key is assigned to. The field's name is also matched when it's
+// tagged params:"-", so that the param can be removed.
+//
+// The fields of an embedded struct without a name of its own are matched as
+// though they're the parent's, as jsonFields does, and as mapstructure does
+// for squashed structs, but only when the parent doesn't have a field with
+// the same name.
+func findField(t reflect.Type, key string, fieldName func(reflect.StructField) string) (reflect.StructField, bool) {
+	var embedded []reflect.Type
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && fieldName(sf) == sf.Name && indirectType(sf.Type).Kind() == reflect.Struct {
+			embedded = append(embedded, indirectType(sf.Type))
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if strings.EqualFold(fieldName(sf), key) || strings.EqualFold(sf.Name, key) {
+			return sf, true
+		}
+	}
+
+	for _, et := range embedded {
+		if sf, ok := findField(et, key, fieldName); ok {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// filterParams removes the params that may not be assigned to the given type
+// from values, and returns their names in bracket notation. A param may not
+// be assigned if its field is tagged params:"-", or if it isn't in the
+// given permitTree. Params that don't belong to any field are left alone,
+// since they're never assigned.
+func filterParams(values map[string]interface{}, t reflect.Type, permit permitTree, fieldName func(reflect.StructField) string, prefix string) []string {
+	if t == nil {
+		return nil
+	}
+
+	var removed []string
+
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+		return nil
+	}
+
+	for key, value := range values {
+		name := key
+		if prefix != "" {
+			name = prefix + "[" + key + "]"
+		}
+
+		// Maps don't have fields, so only the permitted keys are checked.
+		if t.Kind() == reflect.Map {
+			if _, ok := permit.lookup(key); !ok {
+				delete(values, key)
+				removed = append(removed, name)
+			}
+			continue
+		}
+
+		// Fields with a source tag are only assigned from their source,
+		// which bindSources takes care of.
+		sf, ok := findField(t, key, fieldName)
+		if !ok || hasSourceTag(sf) {
+			continue
+		}
+
+		child, permitted := permit.lookup(key)
+		if !permitted || strings.Split(sf.Tag.Get("params"), ",")[0] == "-" {
+			delete(values, key)
+			removed = append(removed, name)
+			continue
+		}
+
+		ft := indirectType(sf.Type)
+		switch value := value.(type) {
+		case map[string]interface{}:
+			removed = append(removed, filterParams(value, ft, child, fieldName, name)...)
+		case []interface{}:
+			if ft.Kind() != reflect.Slice && ft.Kind() != reflect.Array {
+				continue
+			}
+			for i, elem := range value {
+				if m, ok := elem.(map[string]interface{}); ok {
+					removed = append(removed, filterParams(m, ft.Elem(), child, fieldName, name+"["+strconv.Itoa(i)+"]")...)
+				}
+			}
+		}
+	}
+
+	return removed
+}
+
+// filterJSON removes the fields that may not be assigned to the given type
+// from the given JSON object, as filterParams does, and returns the
+// filtered JSON along with the names of the fields that were removed. If
+// the JSON isn't an object, it's returned unchanged.
+func filterJSON(data []byte, t reflect.Type, permit permitTree) ([]byte, []string, error) {
+	var values map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil || values == nil {
+		return data, nil, nil
+	}
+
+	removed := filterParams(values, t, permit, jsonFieldName, "")
+	if len(removed) == 0 {
+		return data, nil, nil
+	}
+
+	filtered, err := json.Marshal(values)
+	return filtered, removed, err
+}
+
+// unpermitted handles the params that were removed because they may not be
+// assigned. If the RejectUnpermittedParams option is set, a ValidationErrors
+// describing each param is returned, otherwise the params are ignored, and
+// logged if the LogUnpermittedParams option is set.
+func (c *context) unpermitted(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	if c.app != nil && c.app.rejectUnpermittedParams {
+		errs := make(ValidationErrors)
+		for _, name := range names {
+			errs.Add(name, "is not allowed")
+		}
+		return errs
+	}
+
+	if c.app != nil && c.app.logUnpermittedParams {
+		log.Warn().Strs("params", names).Str("method", c.r.Method).Str("path", c.r.URL.Path).Msg("ignored unpermitted params")
+	}
+	return nil
+}
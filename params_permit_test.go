@@ -0,0 +1,210 @@
+package seatbelt_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+type profile struct {
+	Name    string
+	Email   string
+	Admin   bool `params:"-"`
+	Address struct {
+		City    string
+		Country string
+	}
+	Items []struct {
+		Name  string
+		Price int
+	}
+}
+
+func newFormRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestContextRequestParamsForbidden(t *testing.T) {
+	r := newFormRequest(url.Values{
+		"name":  {"Jane"},
+		"admin": {"true"},
+		"-":     {"true"},
+	})
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+	var v profile
+	if err := c.Params(&v); err != nil {
+		t.Fatalf("%+v decoding params", err)
+	}
+	if v.Name != "Jane" {
+		t.Fatalf("expected name Jane but got %s", v.Name)
+	}
+	if v.Admin {
+		t.Fatalf("expected a field tagged params:\"-\" not to be assigned")
+	}
+
+	// The same applies to JSON bodies.
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "Jane", "Admin": true}`))
+	r.Header.Set("Content-Type", "application/json")
+	c = seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+	v = profile{}
+	if err := c.Params(&v); err != nil {
+		t.Fatalf("%+v decoding params", err)
+	}
+	if v.Name != "Jane" || v.Admin {
+		t.Fatalf("expected only the name to be assigned but got %+v", v)
+	}
+}
+
+func TestContextRequestParamsPermit(t *testing.T) {
+	r := newFormRequest(url.Values{
+		"name":             {"Jane"},
+		"email":            {"jane@example.com"},
+		"address[city]":    {"Toronto"},
+		"address[country]": {"Canada"},
+		"items[0][name]":   {"Widget"},
+		"items[0][price]":  {"100"},
+	})
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+	var v profile
+	if err := c.Params(&v, seatbelt.Permit("name", "address[city]", "items[name]")); err != nil {
+		t.Fatalf("%+v decoding params", err)
+	}
+
+	if v.Name != "Jane" {
+		t.Fatalf("expected name Jane but got %s", v.Name)
+	}
+	if v.Email != "" {
+		t.Fatalf("expected email not to be permitted but got %s", v.Email)
+	}
+	if v.Address.City != "Toronto" || v.Address.Country != "" {
+		t.Fatalf("expected only the city to be permitted but got %+v", v.Address)
+	}
+	if len(v.Items) != 1 || v.Items[0].Name != "Widget" || v.Items[0].Price != 0 {
+		t.Fatalf("expected only the item names to be permitted but got %+v", v.Items)
+	}
+
+	// Permitting a param permits everything nested beneath it.
+	v = profile{}
+	if err := c.Params(&v, seatbelt.Permit("address")); err != nil {
+		t.Fatalf("%+v decoding params", err)
+	}
+	if v.Address.City != "Toronto" || v.Address.Country != "Canada" || v.Name != "" {
+		t.Fatalf("expected only the address to be permitted but got %+v", v)
+	}
+
+	// Maps only contain the permitted keys.
+	m := make(map[string]interface{})
+	if err := c.Params(&m, seatbelt.Permit("name")); err != nil {
+		t.Fatalf("%+v decoding params", err)
+	}
+	if len(m) != 1 || m["name"] != "Jane" {
+		t.Fatalf("expected only the name to be permitted but got %+v", m)
+	}
+}
+
+func TestRejectUnpermittedParams(t *testing.T) {
+	app := seatbelt.New(seatbelt.Option{RejectUnpermittedParams: true})
+	app.Post("/", func(c seatbelt.Context) error {
+		var v profile
+		if err := c.Params(&v, seatbelt.Permit("name", "admin")); err != nil {
+			verrs, ok := err.(seatbelt.ValidationErrors)
+			if !ok {
+				return err
+			}
+			return c.String(http.StatusUnprocessableEntity, verrs.Error())
+		}
+		return c.NoContent()
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, newFormRequest(url.Values{"name": {"Jane"}}))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected HTTP 204 but got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, newFormRequest(url.Values{
+		"name":          {"Jane"},
+		"admin":         {"true"},
+		"address[city]": {"Toronto"},
+	}))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected HTTP 422 but got %d", w.Code)
+	}
+	if expected := "address is not allowed; admin is not allowed"; w.Body.String() != expected {
+		t.Fatalf("expected %q but got %q", expected, w.Body.String())
+	}
+}
+
+func TestContextRequestParamsForbiddenEmbedded(t *testing.T) {
+	t.Parallel()
+
+	// base is embedded in staff, so that its fields are decoded as though
+	// they're staff's own.
+	type base struct {
+		Admin bool `json:"admin" params:"-"`
+	}
+	type staff struct {
+		base
+		Name string
+	}
+
+	app := seatbelt.New(seatbelt.Option{RejectUnpermittedParams: true})
+
+	var v staff
+	app.Post("/", func(c seatbelt.Context) error {
+		v = staff{}
+		if err := c.Params(&v, seatbelt.Permit("name")); err != nil {
+			return c.String(http.StatusUnprocessableEntity, err.Error())
+		}
+		return c.NoContent()
+	})
+
+	form := newFormRequest(url.Values{"name": {"x"}, "admin": {"true"}})
+
+	body := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "x", "admin": true}`))
+	body.Header.Set("Content-Type", "application/json")
+
+	for _, r := range []*http.Request{form, body} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected HTTP 422 for %s but got %d", r.Header.Get("Content-Type"), w.Code)
+		}
+		if expected := "admin is not allowed"; w.Body.String() != expected {
+			t.Fatalf("expected %q but got %q", expected, w.Body.String())
+		}
+		if v.Admin {
+			t.Fatalf("expected an embedded field tagged params:\"-\" not to be assigned")
+		}
+	}
+
+	// Without a Permit option, the forbidden field is still removed.
+	for _, content := range []string{"application/x-www-form-urlencoded", "application/json"} {
+		payload := "name=x&admin=true"
+		if content == "application/json" {
+			payload = `{"name": "x", "admin": true}`
+		}
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+		r.Header.Set("Content-Type", content)
+		c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+		var u staff
+		if err := c.Params(&u); err != nil {
+			t.Fatalf("%+v decoding params", err)
+		}
+		if u.Name != "x" || u.Admin {
+			t.Fatalf("expected only the name to be assigned from %s but got %+v", content, u)
+		}
+	}
+}
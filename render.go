@@ -7,41 +7,151 @@ import (
 	"html/template"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	texttemplate "text/template"
+	"text/template/parse"
 
 	"github.com/gorilla/csrf"
+	"github.com/rs/zerolog/log"
 )
 
+// A Format describes how a template is parsed, and the Content-Type it is
+// served with.
+type Format int
+
+const (
+	// FormatHTML templates are parsed with html/template and served as
+	// text/html.
+	FormatHTML Format = iota
+
+	// FormatText templates are parsed with text/template, so their output is
+	// never HTML escaped.
+	FormatText
+
+	// FormatXML templates are parsed with text/template, with the output of
+	// every action XML escaped, and served as application/xml. Values of
+	// type template.HTML are written as is.
+	FormatXML
+
+	// FormatSVG templates are parsed like FormatXML templates, and served as
+	// image/svg+xml.
+	FormatSVG
+)
+
+// isText returns true if templates of the given format are parsed with
+// text/template rather than html/template.
+func (f Format) isText() bool {
+	return f == FormatText || f == FormatXML || f == FormatSVG
+}
+
+// ContentType returns the value of the Content-Type header used when
+// serving a template of the given format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatText:
+		return "text/plain"
+	case FormatXML:
+		return "application/xml"
+	case FormatSVG:
+		return "image/svg+xml"
+	default:
+		return "text/html"
+	}
+}
+
+// defaultFormats are the template file extensions that are recognized when
+// no others are configured.
+var defaultFormats = map[string]Format{
+	".html":      FormatHTML,
+	".gohtml":    FormatHTML,
+	".tmpl":      FormatHTML,
+	".html.tmpl": FormatHTML,
+	".txt":       FormatText,
+	".txt.tmpl":  FormatText,
+	".xml":       FormatXML,
+	".svg":       FormatSVG,
+}
+
 // Renderer is an instance of a template renderer.
 type Renderer struct {
-	// templates are our HTML templates.
-	templates map[string]*template.Template
-
-	// textTemplates are our plaintextL templates.
-	textTemplates map[string]*template.Template
+	// set holds the current *templateSet. It is swapped atomically whenever
+	// the templates are reloaded, so that requests that are already
+	// rendering continue to use the templates they started with.
+	set atomic.Value
 
 	// dir is the root of the templates directory.
 	dir string
 
-	// reload, if true, will reload the templates from the filesystem on each
-	// request.
+	// reload, if true, will reload the templates from the filesystem
+	// whenever they change.
 	reload bool
 
+	// watcher watches the template directory for changes when reloading is
+	// enabled.
+	watcher *watcher
+
+	// mu guards reloadErr, onReload, and liveReload.
+	mu sync.RWMutex
+
+	// reloadErr is the error from the most recent attempt to reload the
+	// templates, if it failed.
+	reloadErr error
+
+	// onReload, if set, is called after the templates have been reloaded.
+	onReload func()
+
+	// liveReload, if true, injects the live reload script into every HTML
+	// page.
+	liveReload bool
+
 	// funcs are the HTML template functions passed to the Renderer instance.
 	funcs template.FuncMap
+
+	// formats maps a template file extension to the format it is parsed as.
+	formats map[string]Format
+
+	// ignore is a list of glob patterns for files that are never parsed.
+	ignore []string
+
+	// etag, if true, sends a weak ETag with every HTML page, and responds
+	// with a 304 Not Modified when the client's copy is still fresh.
+	etag bool
+}
+
+// RendererOption is used to configure a Renderer.
+type RendererOption struct {
+	Dir    string           // The directory where the templates reside.
+	Reload bool             // Whether or not to reload templates when they change.
+	Funcs  template.FuncMap // HTML functions.
+
+	// Formats maps template file extensions, such as ".gohtml" or
+	// ".html.tmpl", to the format they are parsed as. These are added to the
+	// default extensions, which are .html, .gohtml, .tmpl, .html.tmpl, .txt,
+	// .txt.tmpl, .xml, and .svg.
+	Formats map[string]Format
+
+	// Ignore is a list of glob patterns, as understood by filepath.Match,
+	// for files that should never be parsed. Each pattern is matched against
+	// both the file's name and its path relative to the template directory.
+	// Hidden files are always ignored.
+	Ignore []string
+
+	// ETag, if true, sends a weak ETag computed from the rendered output of
+	// every successful HTML response, and responds to a matching
+	// If-None-Match header with a 304 Not Modified. Pages that render
+	// something different on every request, such as a CSRF token, never
+	// match.
+	ETag bool
 }
 
 // NewRenderer returns a new instance of a renderer.
 func NewRenderer(dir string, reload bool, funcs ...template.FuncMap) *Renderer {
-	dirPath, err := filepath.Abs(dir)
-	if err != nil {
-		log.Fatalf("%v failed to determine absolute filepath", err)
-	}
-
 	htmlfn := make(template.FuncMap)
 	for _, fn := range funcs {
 		// If you call NewRenderer as pass `nil` into the optional `funcs`
@@ -55,30 +165,168 @@ func NewRenderer(dir string, reload bool, funcs ...template.FuncMap) *Renderer {
 		}
 	}
 
-	re := &Renderer{dir: dirPath, reload: reload, funcs: htmlfn}
+	return NewRendererWithOption(RendererOption{
+		Dir:    dir,
+		Reload: reload,
+		Funcs:  htmlfn,
+	})
+}
+
+// NewRendererWithOption returns a new instance of a renderer configured with
+// the given option.
+//
+// If reloading is enabled, the template directory is watched for changes
+// until Close is called.
+func NewRendererWithOption(opt RendererOption) *Renderer {
+	re := newRenderer(opt)
+
+	// Start watching before parsing, so that a change made while the
+	// templates are being parsed is still picked up.
+	if re.reload {
+		re.watcher = newWatcher(re.reloadTemplates, re.dir)
+	}
 
 	if err := re.parseTemplates(); err != nil {
+		re.Close()
 		panic(err)
 	}
 
 	return re
 }
 
+// Close stops watching the template directory for changes. It is only
+// necessary to call Close if reloading is enabled.
+func (r *Renderer) Close() error {
+	if r.watcher != nil {
+		r.watcher.close()
+	}
+	return nil
+}
+
+// newRenderer returns a new instance of a renderer that has not yet parsed
+// its templates.
+func newRenderer(opt RendererOption) *Renderer {
+	dirPath, err := filepath.Abs(opt.Dir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to determine absolute filepath")
+	}
+
+	if opt.Funcs == nil {
+		opt.Funcs = make(template.FuncMap)
+	}
+
+	formats := make(map[string]Format)
+	for ext, f := range defaultFormats {
+		formats[ext] = f
+	}
+	for ext, f := range opt.Formats {
+		formats[ext] = f
+	}
+
+	// Hidden files, including the swap files of most editors, are ignored
+	// no matter what else is.
+	ignore := append([]string{".*"}, opt.Ignore...)
+
+	return &Renderer{
+		dir:     dirPath,
+		reload:  opt.Reload,
+		funcs:   opt.Funcs,
+		formats: formats,
+		ignore:  ignore,
+		etag:    opt.ETag,
+	}
+}
+
 // A templateFile is used during the parsing of our templates to save each
 // regular, non-layout template until we can parse them within a layout
 // context.
 type templateFile struct {
 	name    string
+	rel     string
+	format  Format
 	content string
 }
 
-// parseTemplates reads and parses the templates from the filesystem.
-func (r *Renderer) parseTemplates() error {
-	htmlLayouts := template.New("html_layouts")
-	textLayouts := template.New("text_layouts")
+// isLayout returns true if the template file resides in the layouts
+// directory.
+func (tf templateFile) isLayout() bool {
+	return path.Dir(tf.rel) == "layouts"
+}
+
+// A templateSet is a complete set of parsed templates.
+type templateSet struct {
+	html map[Format]map[string]*htmlTemplate
+	text map[Format]map[string]*texttemplate.Template
+}
+
+// An htmlTemplate is a parsed HTML template, along with a pool of copies of
+// it that have already been executed.
+//
+// The template can't be executed directly, since each request renders it
+// with its own funcs, and html/template escapes every copy of a template the
+// first time it's executed. Reusing the copies means that only the first
+// few requests pay for escaping.
+type htmlTemplate struct {
+	tpl  *template.Template
+	pool sync.Pool
+}
+
+// get returns a copy of the template that isn't being executed by any other
+// request, with the given funcs.
+func (t *htmlTemplate) get(funcs template.FuncMap) (*template.Template, error) {
+	tpl, ok := t.pool.Get().(*template.Template)
+	if !ok {
+		var err error
+		if tpl, err = t.tpl.Clone(); err != nil {
+			return nil, err
+		}
+	}
+	return tpl.Funcs(funcs), nil
+}
+
+// put returns a copy of the template to the pool once it's been executed.
+func (t *htmlTemplate) put(tpl *template.Template) {
+	t.pool.Put(tpl)
+}
 
-	htmlTemplateFiles := make([]templateFile, 0)
-	textTemplateFiles := make([]templateFile, 0)
+// match returns the template name and format of the file at the given path,
+// relative to the template directory. If the file isn't a template, the
+// returned boolean is false.
+func (r *Renderer) match(rel string) (string, Format, bool) {
+	rel = filepath.ToSlash(rel)
+	base := path.Base(rel)
+
+	for _, pattern := range r.ignore {
+		if ok, _ := path.Match(pattern, base); ok {
+			return "", 0, false
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return "", 0, false
+		}
+	}
+
+	// Find the longest matching extension, so that a file named
+	// "index.html.tmpl" matches ".html.tmpl" rather than ".tmpl".
+	ext := ""
+	for e := range r.formats {
+		if len(e) > len(ext) && len(base) > len(e) && strings.HasSuffix(base, e) {
+			ext = e
+		}
+	}
+	if ext == "" {
+		return "", 0, false
+	}
+
+	return rel[0 : len(rel)-len(ext)], r.formats[ext], true
+}
+
+// walk reads every template file from the template directory.
+func (r *Renderer) walk() ([]templateFile, error) {
+	files := make([]templateFile, 0)
+
+	// Files with different extensions can have the same name, ie,
+	// "index.html" and "index.gohtml", and only one of them can be used.
+	seen := make(map[Format]map[string]string)
 
 	if err := filepath.Walk(r.dir, func(path string, info os.FileInfo, _ error) error {
 		// Fix same-extension-dirs bug: some dir might be named to:
@@ -95,122 +343,304 @@ func (r *Renderer) parseTemplates() error {
 			return err
 		}
 
-		ext := ""
-		if strings.Contains(rel, ".") {
-			ext = filepath.Ext(rel)
+		// Skip anything that isn't a template, such as a stray .DS_Store or
+		// README.md in the views folder.
+		name, format, ok := r.match(rel)
+		if !ok {
+			return nil
+		}
+
+		if seen[format] == nil {
+			seen[format] = make(map[string]string)
 		}
-		if ext != ".txt" && ext != ".html" {
-			return fmt.Errorf("templates must end in .html or .txt, got %s", ext)
+		if other, ok := seen[format][name]; ok {
+			return errors.New("the templates " + other + " and " + filepath.ToSlash(rel) + " both have the name " + name)
 		}
+		seen[format][name] = filepath.ToSlash(rel)
 
 		buf, err := ioutil.ReadFile(path)
 		if err != nil {
 			return err
 		}
 
-		name := (rel[0 : len(rel)-len(ext)])
+		files = append(files, templateFile{
+			name:    name,
+			rel:     filepath.ToSlash(rel),
+			format:  format,
+			content: string(buf),
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// defaultFuncs returns a copy of the given funcs, with the defaults for any
+// request specific funcs, and the built in funcs, added if they haven't
+// already been assigned.
+func defaultFuncs(funcs template.FuncMap) template.FuncMap {
+	fm := make(template.FuncMap)
+	for fn, impl := range funcs {
+		fm[fn] = impl
+	}
+
+	// These must be checked to see if they've previously been assigned, or
+	// else we risk overwriting the actual implementation of the function.
+	if _, ok := fm["csrf"]; !ok {
+		fm["csrf"] = func() template.HTML {
+			return ""
+		}
+	}
+	if _, ok := fm["flashes"]; !ok {
+		fm["flashes"] = func() map[string]interface{} {
+			return nil
+		}
+	}
+	if _, ok := fm["field_error"]; !ok {
+		fm["field_error"] = func(string) string {
+			return ""
+		}
+	}
+	if _, ok := fm["old"]; !ok {
+		fm["old"] = func(string) string {
+			return ""
+		}
+	}
+	if _, ok := fm["locals"]; !ok {
+		fm["locals"] = func() map[string]interface{} {
+			return nil
+		}
+	}
+	if _, ok := fm["paginate"]; !ok {
+		fm["paginate"] = paginate
+	}
 
-		// On Windows, replace the OS-specific path separator "\" with the
-		// conventional Linux/Mac one.
-		name = strings.Replace(name, `\`, "/", -1)
+	return fm
+}
 
-		// If we're not in the layouts directory, we don't want to parse these
-		// regular templates until we have parsed all layout templates. To
-		// support this, we'll save these templates unparsed.
-		if folder := filepath.Dir(rel); folder != "layouts" {
-			if ext == ".html" {
-				htmlTemplateFiles = append(htmlTemplateFiles, templateFile{
-					name:    name,
-					content: string(buf),
+// load parses the given template files using the given funcs.
+func load(files []templateFile, funcs template.FuncMap) (*templateSet, error) {
+	// Every format has its own set of layouts, since each is parsed with a
+	// different escaping context.
+	htmlLayouts := make(map[Format]*template.Template)
+	textLayouts := make(map[Format]*texttemplate.Template)
+
+	// The trees that have already been XML escaped, since the trees of the
+	// layouts are shared by every copy of them.
+	escaped := make(map[*parse.Tree]bool)
+
+	for _, tf := range files {
+		if tf.format.isText() {
+			layouts, ok := textLayouts[tf.format]
+			if !ok {
+				layouts = texttemplate.New("text_layouts").Funcs(texttemplate.FuncMap{
+					xmlEscaperName: xmlEscaper,
 				})
+				textLayouts[tf.format] = layouts
 			}
-			if ext == ".txt" {
-				textTemplateFiles = append(textTemplateFiles, templateFile{
-					name:    name,
-					content: string(buf),
-				})
+			if _, err := layouts.New(tf.name).Funcs(texttemplate.FuncMap(funcs)).Parse(tf.content); err != nil {
+				return nil, err
 			}
+			continue
 		}
 
-		// Add user supplied HTML functions, and add our defaults.
-		//
-		// These must be checked to see if they've previously been assigned,
-		// or else we risk overwriting the actual implementation of the
-		// function.
-		//
-		// For example, if a function is defined prior to the templates being
-		// parsed, but is then redefined inside the gloabl `serveContext`
-		// handler, we can overwrite the actual implementation on subsequent
-		// calls to this function when reloading is enabled, as this logic
-		// executes once at server startup, but also after template function
-		// assignment.
-		//
-		// Basically, just be careful when changing these. There's probably a
-		// safer way to register these funcs that rely on request specific
-		// data, but this works for now.
-		funcs := r.funcs
-		if _, ok := funcs["csrf"]; !ok {
-			funcs["csrf"] = func() template.HTML {
-				return ""
-			}
+		layouts, ok := htmlLayouts[tf.format]
+		if !ok {
+			layouts = template.New("html_layouts")
+			htmlLayouts[tf.format] = layouts
 		}
-		if _, ok := funcs["flashes"]; !ok {
-			funcs["flashes"] = func() map[string]interface{} {
-				return nil
-			}
+		if _, err := layouts.New(tf.name).Funcs(funcs).Parse(tf.content); err != nil {
+			return nil, err
 		}
+	}
 
-		if ext == ".html" {
-			if _, err := htmlLayouts.New(name).Funcs(funcs).Parse(string(buf)); err != nil {
-				return err
-			}
-		}
-		if ext == ".txt" {
-			if _, err := textLayouts.New(name).Funcs(funcs).Parse(string(buf)); err != nil {
-				return err
-			}
+	for format, layouts := range textLayouts {
+		if format != FormatText {
+			escapeXML(layouts, escaped)
 		}
+	}
 
-		return nil
-	}); err != nil {
-		return err
+	set := &templateSet{
+		html: make(map[Format]map[string]*htmlTemplate),
+		text: make(map[Format]map[string]*texttemplate.Template),
 	}
 
-	htmlTemplates := make(map[string]*template.Template)
-	textTemplates := make(map[string]*template.Template)
+	// Now that all of the layouts have been parsed, parse each regular
+	// template within its own copy of the layouts, so that the blocks it
+	// defines don't clash with any other template's.
+	for _, tf := range files {
+		if tf.isLayout() {
+			continue
+		}
 
-	for _, tf := range htmlTemplateFiles {
-		t, err := htmlLayouts.Clone()
-		if err != nil {
-			return err
+		if tf.format.isText() {
+			t, err := textLayouts[tf.format].Clone()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := t.Parse(tf.content); err != nil {
+				return nil, err
+			}
+			if tf.format != FormatText {
+				escapeXML(t, escaped)
+			}
+
+			if set.text[tf.format] == nil {
+				set.text[tf.format] = make(map[string]*texttemplate.Template)
+			}
+			set.text[tf.format][tf.name] = t
+			continue
 		}
 
+		t, err := htmlLayouts[tf.format].Clone()
+		if err != nil {
+			return nil, err
+		}
 		if _, err := t.Parse(tf.content); err != nil {
-			return err
+			return nil, err
 		}
 
-		htmlTemplates[tf.name] = t
+		if set.html[tf.format] == nil {
+			set.html[tf.format] = make(map[string]*htmlTemplate)
+		}
+		set.html[tf.format][tf.name] = &htmlTemplate{tpl: t}
 	}
 
-	for _, tf := range textTemplateFiles {
-		t, err := textLayouts.Clone()
-		if err != nil {
-			return err
+	return set, nil
+}
+
+// xmlEscaperName is the name of the func that escapes the output of every
+// action in XML and SVG templates.
+const xmlEscaperName = "_seatbelt_xmlescaper"
+
+// xmlEscaper escapes the given value for use in XML text or a quoted
+// attribute, unless it's template.HTML.
+func xmlEscaper(args ...interface{}) string {
+	if len(args) == 1 {
+		switch v := args[0].(type) {
+		case nil:
+			return ""
+		case template.HTML:
+			return string(v)
 		}
+	}
+	return template.HTMLEscapeString(fmt.Sprint(args...))
+}
 
-		if _, err := t.Parse(tf.content); err != nil {
-			return err
+// escapeXML adds the xmlEscaper to the end of the pipeline of every action
+// in the given template and its associated templates, so that their output
+// is escaped as html/template would, but without treating the template as
+// HTML. That would escape the XML declaration, since it isn't valid HTML.
+func escapeXML(t *texttemplate.Template, escaped map[*parse.Tree]bool) {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil || escaped[tmpl.Tree] {
+			continue
+		}
+		escaped[tmpl.Tree] = true
+		escapeXMLNode(tmpl.Tree.Root)
+	}
+}
+
+// escapeXMLNode adds the xmlEscaper to every action within the given node.
+func escapeXMLNode(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeXMLNode(child)
 		}
+	case *parse.IfNode:
+		escapeXMLNode(n.List)
+		escapeXMLNode(n.ElseList)
+	case *parse.RangeNode:
+		escapeXMLNode(n.List)
+		escapeXMLNode(n.ElseList)
+	case *parse.WithNode:
+		escapeXMLNode(n.List)
+		escapeXMLNode(n.ElseList)
+	case *parse.ActionNode:
+		// Actions that only declare or assign variables don't output
+		// anything.
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(xmlEscaperName).SetPos(n.Pos)},
+		})
+	}
+}
+
+// parseTemplates reads and parses the templates from the filesystem.
+func (r *Renderer) parseTemplates() error {
+	files, err := r.walk()
+	if err != nil {
+		return err
+	}
 
-		textTemplates[tf.name] = t
+	// Add user supplied HTML functions, and add our defaults.
+	set, err := load(files, defaultFuncs(r.funcs))
+	if err != nil {
+		return err
 	}
 
-	r.templates = htmlTemplates
-	r.textTemplates = textTemplates
+	r.set.Store(set)
 
 	return nil
 }
 
+// templateSet returns the current set of templates.
+func (r *Renderer) templateSet() *templateSet {
+	return r.set.Load().(*templateSet)
+}
+
+// reloadTemplates reparses the templates after they've changed. If they
+// fail to parse, the last good set of templates is kept, and the error is
+// shown in the browser until the problem is fixed.
+func (r *Renderer) reloadTemplates() {
+	err := r.parseTemplates()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to reload templates")
+	}
+
+	r.mu.Lock()
+	r.reloadErr = err
+	onReload := r.onReload
+	r.mu.Unlock()
+
+	if onReload != nil {
+		onReload()
+	}
+}
+
+// reloadErrorHTML is injected into HTML pages while the templates fail to
+// reload.
+const reloadErrorHTML = `<pre style="position:fixed;bottom:0;left:0;right:0;z-index:2147483647;margin:0;padding:1em;max-height:50vh;overflow:auto;background:#fee;color:#900;border-top:2px solid #900;white-space:pre-wrap;">`
+
+// injectHTML inserts the given snippet before the closing body tag of an
+// HTML page, or at the end of it if there isn't one.
+func injectHTML(buf *bytes.Buffer, snippet string) {
+	page := buf.Bytes()
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		buf.WriteString(snippet)
+		return
+	}
+
+	injected := make([]byte, 0, len(page)+len(snippet))
+	injected = append(injected, page[:i]...)
+	injected = append(injected, snippet...)
+	injected = append(injected, page[i:]...)
+
+	buf.Reset()
+	buf.Write(injected)
+}
+
 // RenderOption contains the optional options for rendering templates.
 type RenderOption struct {
 	// The Layout to use when rendering the template. The default is
@@ -220,19 +650,94 @@ type RenderOption struct {
 	// Status is the HTTP status code to send when rendering a template. The
 	// default is 200.
 	Status int
+
+	// Format is the format of the template to render. The default is
+	// FormatHTML.
+	Format Format
+
+	// Block is the name of a single block to render without the layout, ie,
+	// "main" to render only what the template defines with
+	// {{ define "main" }}. This is useful for partial page updates. When
+	// Block is set, Layout is ignored.
+	Block string
+}
+
+// newRenderOption returns the last of the given render options, with its
+// defaults set.
+func newRenderOption(opts []RenderOption) RenderOption {
+	var opt RenderOption
+	for _, o := range opts {
+		opt = o
+	}
+
+	if opt.Layout == "" {
+		opt.Layout = "application"
+	}
+	if opt.Status == 0 {
+		opt.Status = 200
+	}
+
+	return opt
+}
+
+// entrypoint returns the name of the template to execute for the given
+// option.
+func (o RenderOption) entrypoint() string {
+	if o.Block != "" {
+		return o.Block
+	}
+	return "layouts/" + o.Layout
 }
 
 // HTML writes an HTML template to a buffer.
 //
 // The name of the layout does **not** require the "layouts/" prefix, unlike
 // other templates.
+//
+// XML and SVG templates can be rendered by setting the Format of the given
+// RenderOption.
 func (r *Renderer) HTML(w io.Writer, req *http.Request, name string, data interface{}, opts ...RenderOption) error {
-	opt := RenderOption{
-		Layout: "application",
-		Status: 200,
+	return r.html(w, req, name, data, nil, opts...)
+}
+
+// HTMLString renders an HTML template to a string. The request may be nil,
+// in which case the csrf template func renders nothing.
+//
+// This is useful for rendering HTML email bodies, or fragments that are
+// cached.
+func (r *Renderer) HTMLString(req *http.Request, name string, data interface{}, opts ...RenderOption) (string, error) {
+	buf, err := r.execute(req, name, data, nil, newRenderOption(opts))
+	if err != nil {
+		return "", err
 	}
-	for _, o := range opts {
-		opt = o
+	return buf.String(), nil
+}
+
+// html writes an HTML template to a buffer, with the given request specific
+// funcs in addition to the renderer's funcs.
+func (r *Renderer) html(w io.Writer, req *http.Request, name string, data interface{}, funcs template.FuncMap, opts ...RenderOption) error {
+	opt := newRenderOption(opts)
+
+	buf, err := r.execute(req, name, data, funcs, opt)
+	if err != nil {
+		return err
+	}
+
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.Header().Set("Content-Type", opt.Format.ContentType())
+		if r.etag && opt.Status == http.StatusOK && setETag(rw, req, buf.Bytes()) {
+			return nil
+		}
+		rw.WriteHeader(opt.Status)
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// execute renders an HTML, XML, or SVG template to a buffer.
+func (r *Renderer) execute(req *http.Request, name string, data interface{}, funcs template.FuncMap, opt RenderOption) (*bytes.Buffer, error) {
+	if opt.Format == FormatText {
+		return nil, errors.New("the template " + name + " is plain text, and must be rendered with Text")
 	}
 
 	// If data is nil, it'll cause panics when trying to render a template that
@@ -242,45 +747,78 @@ func (r *Renderer) HTML(w io.Writer, req *http.Request, name string, data interf
 		data = make(map[string]interface{})
 	}
 
+	var reloadErr error
+	var liveReload bool
 	if r.reload {
-		if err := r.parseTemplates(); err != nil {
-			return err
-		}
+		r.mu.RLock()
+		reloadErr, liveReload = r.reloadErr, r.liveReload
+		r.mu.RUnlock()
 	}
 
 	buf := &bytes.Buffer{}
-	tpl, ok := r.templates[name]
-	if !ok {
-		return errors.New("the template " + name + " does not exist")
+	set := r.templateSet()
+	_, isHTML := set.html[opt.Format][name]
+	_, isText := set.text[opt.Format][name]
+	if !isHTML && !isText {
+		if reloadErr != nil {
+			return nil, reloadErr
+		}
+		return nil, errors.New("the template " + name + " does not exist")
 	}
 
 	// Create a new func map that has the CSRF func with the
 	// implementation, in addition to the user provided funcs if there are
-	// any.
-	contextualFuncMap := make(template.FuncMap)
-	for fn, impl := range r.funcs {
-		contextualFuncMap[fn] = impl
-	}
+	// any. Every request specific func is replaced, even those that fall
+	// back to their defaults, since the copy of the template may have last
+	// been used by another request.
+	contextualFuncMap := defaultFuncs(r.funcs)
 	contextualFuncMap["csrf"] = func() template.HTML {
 		if req == nil {
 			return ""
 		}
 		return csrf.TemplateField(req)
 	}
+	for fn, impl := range funcs {
+		contextualFuncMap[fn] = impl
+	}
 
-	// Provide those funcs.
-	tpl.Funcs(contextualFuncMap)
+	// Provide those funcs to a copy of the template, since the template is
+	// shared between every request that's rendering it.
+	if isText {
+		tpl, err := set.text[opt.Format][name].Clone()
+		if err != nil {
+			return nil, err
+		}
+		tpl.Funcs(texttemplate.FuncMap(contextualFuncMap))
 
-	if err := tpl.ExecuteTemplate(buf, "layouts/"+opt.Layout, data); err != nil {
-		return err
+		if err := tpl.ExecuteTemplate(buf, opt.entrypoint(), data); err != nil {
+			return nil, err
+		}
+		return buf, nil
 	}
 
-	if rw, ok := w.(http.ResponseWriter); ok {
-		rw.Header().Set("Content-Type", "text/html")
-		rw.WriteHeader(opt.Status)
+	ht := set.html[opt.Format][name]
+	tpl, err := ht.get(contextualFuncMap)
+	if err != nil {
+		return nil, err
 	}
-	_, err := buf.WriteTo(w)
-	return err
+	err = tpl.ExecuteTemplate(buf, opt.entrypoint(), data)
+	ht.put(tpl)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only inject into complete pages, never into a single block.
+	if opt.Format == FormatHTML && opt.Block == "" {
+		if reloadErr != nil {
+			injectHTML(buf, reloadErrorHTML+template.HTMLEscapeString(reloadErr.Error())+"</pre>")
+		}
+		if liveReload {
+			injectHTML(buf, liveReloadScript)
+		}
+	}
+
+	return buf, nil
 }
 
 // Text renders the template with the given name to a string. It will render
@@ -289,33 +827,27 @@ func (r *Renderer) HTML(w io.Writer, req *http.Request, name string, data interf
 // This should be used when rendering a template outside the context of an
 // HTTP request, ie, rendering an email template, or a plain text template.
 func (r *Renderer) Text(name string, data interface{}, opts ...RenderOption) (string, error) {
-	opt := RenderOption{
-		Layout: "application",
-	}
-	for _, o := range opts {
-		opt = o
-	}
-
-	if r.reload {
-		if err := r.parseTemplates(); err != nil {
-			return "", err
-		}
-	}
+	opt := newRenderOption(opts)
 
 	buf := &bytes.Buffer{}
-	tpl, ok := r.textTemplates[name]
+	tpl, ok := r.templateSet().text[FormatText][name]
 	if !ok {
 		return "", errors.New("the template " + name + " does not exist")
 	}
 
-	// Like in the HTML method, add user provided funcs.
-	contextualFuncMap := make(template.FuncMap)
+	// Like in the HTML method, add user provided funcs to a copy of the
+	// template.
+	contextualFuncMap := make(texttemplate.FuncMap)
 	for fn, impl := range r.funcs {
 		contextualFuncMap[fn] = impl
 	}
+	tpl, err := tpl.Clone()
+	if err != nil {
+		return "", err
+	}
 	tpl.Funcs(contextualFuncMap)
 
-	if err := tpl.ExecuteTemplate(buf, "layouts/"+opt.Layout, data); err != nil {
+	if err := tpl.ExecuteTemplate(buf, opt.entrypoint(), data); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
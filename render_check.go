@@ -0,0 +1,295 @@
+package seatbelt
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// A TemplateError describes a problem found in a template file while
+// checking it.
+type TemplateError struct {
+	File    string // The path of the template file, relative to the template directory.
+	Line    int    // The line the problem was found on, or zero if unknown.
+	Message string // A description of the problem.
+}
+
+// Error implements the error interface.
+func (e *TemplateError) Error() string {
+	if e.Line == 0 {
+		return e.File + ": " + e.Message
+	}
+	return e.File + ":" + strconv.Itoa(e.Line) + ": " + e.Message
+}
+
+// TemplateErrors is the list of problems found while checking templates.
+type TemplateErrors []*TemplateError
+
+// Error implements the error interface.
+func (e TemplateErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// CheckOption contains the optional options for checking templates.
+type CheckOption struct {
+	// Data maps template names to sample data. Every template is executed
+	// against its layout with the "missingkey=error" option, so that any
+	// key the template uses that its data doesn't have, ie, a typo like
+	// {{ .Titel }}, is reported. Templates without sample data are executed
+	// with empty data, so every key they use is reported until they're
+	// given sample data.
+	Data map[string]interface{}
+
+	// The Layout to execute templates against. The default is
+	// `application`.
+	Layout string
+
+	// IgnoreUnknownFuncs replaces any function that hasn't been registered
+	// with a no-op, rather than reporting it. This is useful when checking
+	// templates outside of the application that registers its funcs, ie,
+	// from the command line.
+	IgnoreUnknownFuncs bool
+}
+
+// templateErrorRe matches the location in errors returned from the
+// text/template and html/template packages, ie,
+//
+//	template: home/index:4: function "lowr" not defined
+//	template: html_layouts:3:5: executing "main" at <.Name>: ...
+var templateErrorRe = regexp.MustCompile(`^(?:html/)?template: ?([^:]+):(\d+):(?:\d+:)? ?(.*)$`)
+
+// unknownFuncRe matches the parse error for a function that hasn't been
+// defined.
+var unknownFuncRe = regexp.MustCompile(`function "([^"]+)" not defined`)
+
+// Check parses every template, reports any references to templates that are
+// not defined, and executes each template against its layout, using its
+// sample data if it has any. If any problems are found, the returned error
+// is TemplateErrors.
+//
+// Check is intended to be called from a test, so that broken templates are
+// caught before they fail at request time.
+func (r *Renderer) Check(opts ...CheckOption) error {
+	var opt CheckOption
+	for _, o := range opts {
+		opt = o
+	}
+	if opt.Layout == "" {
+		opt.Layout = "application"
+	}
+
+	files, err := r.walk()
+	if err != nil {
+		return err
+	}
+
+	// The no-ops for unknown funcs are added to funcs as they're found, so
+	// that every file, and the set they're loaded into, can use them.
+	funcs := defaultFuncs(r.funcs)
+	errs := make(TemplateErrors, 0)
+
+	// Parse each file on its own first, so that we can report every syntax
+	// error instead of only the first one.
+	trees := make(map[string][]*parse.Tree)
+	defined := make(map[Format]map[string]bool)
+	for _, tf := range files {
+		t, unknown, err := parseStandalone(tf, funcs, opt.IgnoreUnknownFuncs)
+		for _, name := range unknown {
+			funcs[name] = noop
+		}
+		if err != nil {
+			errs = append(errs, newTemplateError(tf.rel, err))
+			continue
+		}
+
+		if defined[tf.format] == nil {
+			defined[tf.format] = make(map[string]bool)
+		}
+		for _, tmpl := range t.Templates() {
+			if tmpl.Tree == nil {
+				continue
+			}
+			defined[tf.format][tmpl.Name()] = true
+			trees[tf.rel] = append(trees[tf.rel], tmpl.Tree)
+		}
+	}
+
+	// Report every {{ template "name" }} that refers to a template that
+	// isn't defined in any file of the same format.
+	for _, tf := range files {
+		for _, tree := range trees[tf.rel] {
+			for _, node := range templateNodes(tree.Root) {
+				if defined[tf.format][node.Name] {
+					continue
+				}
+
+				line := 0
+				if loc, _ := tree.ErrorContext(node); loc != "" {
+					if parts := strings.Split(loc, ":"); len(parts) > 1 {
+						line, _ = strconv.Atoi(parts[1])
+					}
+				}
+				errs = append(errs, &TemplateError{
+					File:    tf.rel,
+					Line:    line,
+					Message: fmt.Sprintf("template %q is not defined", node.Name),
+				})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			return errs[i].File < errs[j].File
+		})
+		return errs
+	}
+
+	set, err := load(files, funcs)
+	if err != nil {
+		return err
+	}
+
+	// Execute every template in strict mode, with empty data if it doesn't
+	// have any sample data.
+	const option = "missingkey=error"
+	for _, tf := range files {
+		if tf.isLayout() {
+			continue
+		}
+
+		data, ok := opt.Data[tf.name]
+		if !ok {
+			data = make(map[string]interface{})
+		}
+
+		var execErr error
+		if tf.format.isText() {
+			t, err := set.text[tf.format][tf.name].Clone()
+			if err != nil {
+				return err
+			}
+			execErr = t.Option(option).ExecuteTemplate(ioutil.Discard, "layouts/"+opt.Layout, data)
+		} else {
+			t, err := set.html[tf.format][tf.name].tpl.Clone()
+			if err != nil {
+				return err
+			}
+			execErr = t.Option(option).ExecuteTemplate(ioutil.Discard, "layouts/"+opt.Layout, data)
+		}
+		if execErr == nil {
+			continue
+		}
+
+		// Errors in the blocks a template defines are reported against the
+		// layouts it was parsed into, so map those back onto its own file.
+		terr := newTemplateError(tf.rel, execErr)
+		if m := templateErrorRe.FindStringSubmatch(execErr.Error()); m != nil {
+			for _, other := range files {
+				if other.name == m[1] && other.format == tf.format {
+					terr.File = other.rel
+				}
+			}
+		}
+		errs = append(errs, terr)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CheckTemplates checks the templates of a renderer configured with the given
+// option, without requiring that they parse successfully beforehand. See
+// Renderer.Check for details.
+func CheckTemplates(ropt RendererOption, opts ...CheckOption) error {
+	if ropt.Funcs == nil {
+		ropt.Funcs = make(template.FuncMap)
+	}
+	return newRenderer(ropt).Check(opts...)
+}
+
+// noop replaces unknown funcs when they're ignored.
+func noop(...interface{}) string {
+	return ""
+}
+
+// parseStandalone parses a single template file on its own. If
+// ignoreUnknown is true, any undefined function is replaced with a no-op
+// and parsing is retried. The names of the replaced functions are returned,
+// even if parsing fails for another reason.
+func parseStandalone(tf templateFile, funcs template.FuncMap, ignoreUnknown bool) (*texttemplate.Template, []string, error) {
+	fm := make(texttemplate.FuncMap, len(funcs))
+	for name, fn := range funcs {
+		fm[name] = fn
+	}
+
+	var unknown []string
+	for {
+		t, err := texttemplate.New(tf.name).Funcs(fm).Parse(tf.content)
+		if err == nil {
+			return t, unknown, nil
+		}
+
+		m := unknownFuncRe.FindStringSubmatch(err.Error())
+		if !ignoreUnknown || m == nil {
+			return nil, unknown, err
+		}
+		if _, ok := fm[m[1]]; ok {
+			return nil, unknown, err
+		}
+		fm[m[1]] = noop
+		unknown = append(unknown, m[1])
+	}
+}
+
+// newTemplateError creates a TemplateError for the given file from an error
+// returned by the template packages.
+func newTemplateError(rel string, err error) *TemplateError {
+	m := templateErrorRe.FindStringSubmatch(err.Error())
+	if m == nil {
+		return &TemplateError{File: rel, Message: err.Error()}
+	}
+
+	line, _ := strconv.Atoi(m[2])
+	return &TemplateError{File: rel, Line: line, Message: m[3]}
+}
+
+// templateNodes returns every {{ template }} action within the given node.
+func templateNodes(node parse.Node) []*parse.TemplateNode {
+	var nodes []*parse.TemplateNode
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			nodes = append(nodes, templateNodes(child)...)
+		}
+	case *parse.IfNode:
+		nodes = append(nodes, templateNodes(n.List)...)
+		nodes = append(nodes, templateNodes(n.ElseList)...)
+	case *parse.RangeNode:
+		nodes = append(nodes, templateNodes(n.List)...)
+		nodes = append(nodes, templateNodes(n.ElseList)...)
+	case *parse.WithNode:
+		nodes = append(nodes, templateNodes(n.List)...)
+		nodes = append(nodes, templateNodes(n.ElseList)...)
+	case *parse.TemplateNode:
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
@@ -0,0 +1,135 @@
+package seatbelt_test
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestRendererCheck(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid templates", func(t *testing.T) {
+		r := seatbelt.NewRenderer("testdata", false, template.FuncMap{
+			"lower": strings.ToLower,
+		})
+
+		if err := r.Check(); err != nil {
+			t.Fatalf("%+v checking valid templates", err)
+		}
+	})
+
+	t.Run("undefined template reference", func(t *testing.T) {
+		dir := writeTemplates(t, map[string]string{
+			"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+			"home/partial.html":        `<p>partial</p>`,
+			"home/index.html":          "{{ define \"main\" }}\n  {{ template \"home/partal\" . }}\n{{ end }}",
+		})
+
+		err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: dir})
+
+		var terrs seatbelt.TemplateErrors
+		if !errors.As(err, &terrs) {
+			t.Fatalf("expected template errors but got %+v", err)
+		}
+		if len(terrs) != 1 {
+			t.Fatalf("expected one error but got %d: %v", len(terrs), terrs)
+		}
+		if terr := terrs[0]; terr.File != "home/index.html" || terr.Line != 2 {
+			t.Fatalf("expected error at home/index.html:2 but got %s", terr)
+		}
+	})
+
+	t.Run("syntax errors in every file", func(t *testing.T) {
+		dir := writeTemplates(t, map[string]string{
+			"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+			"a/index.html":             `{{ define "main" }}{{ .Name }{{ end }}`,
+			"b/index.html":             `{{ define "main" }}{{ lowr .Name }}{{ end }}`,
+		})
+
+		err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: dir})
+
+		var terrs seatbelt.TemplateErrors
+		if !errors.As(err, &terrs) {
+			t.Fatalf("expected template errors but got %+v", err)
+		}
+		if len(terrs) != 2 {
+			t.Fatalf("expected two errors but got %d: %v", len(terrs), terrs)
+		}
+
+		if err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: dir}, seatbelt.CheckOption{
+			IgnoreUnknownFuncs: true,
+		}); err == nil || strings.Contains(err.Error(), "lowr") {
+			t.Fatalf("expected only the syntax error but got %v", err)
+		}
+	})
+
+	t.Run("missing key in sample data", func(t *testing.T) {
+		dir := writeTemplates(t, map[string]string{
+			"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+			"home/index.html":          "{{ define \"main\" }}\n{{ .Title }}\n{{ .Missing }}\n{{ end }}",
+		})
+
+		err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: dir}, seatbelt.CheckOption{
+			Data: map[string]interface{}{
+				"home/index": map[string]interface{}{"Title": "Home"},
+			},
+		})
+
+		var terrs seatbelt.TemplateErrors
+		if !errors.As(err, &terrs) {
+			t.Fatalf("expected template errors but got %+v", err)
+		}
+		if terr := terrs[0]; terr.File != "home/index.html" || terr.Line != 3 || !strings.Contains(terr.Message, "Missing") {
+			t.Fatalf("expected missing key error at home/index.html:3 but got %s", terr)
+		}
+	})
+
+	t.Run("unknown funcs with sample data", func(t *testing.T) {
+		dir := writeTemplates(t, map[string]string{
+			"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+			"home/index.html":          `{{ define "main" }}{{ shout .Title }}{{ end }}`,
+		})
+
+		if err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: dir}, seatbelt.CheckOption{
+			Data: map[string]interface{}{
+				"home/index": map[string]interface{}{"Title": "Home"},
+			},
+			IgnoreUnknownFuncs: true,
+		}); err != nil {
+			t.Fatalf("%+v checking templates with unknown funcs", err)
+		}
+	})
+
+	t.Run("missing keys without sample data", func(t *testing.T) {
+		// The attribute is only closed when the condition is true, which
+		// html/template can't escape no matter what the data is.
+		dir := writeTemplates(t, map[string]string{
+			"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+			"home/about.html":          "{{ define \"main\" }}\n{{ lower .Titel }}\n{{ end }}",
+			"home/index.html":          `{{ define "main" }}<a href="/{{ if .Admin }}admin">{{ end }}{{ end }}`,
+			"home/plain.html":          `{{ define "main" }}{{ lower "plain" }}{{ end }}`,
+		})
+
+		err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: dir, Funcs: template.FuncMap{
+			"lower": strings.ToLower,
+		}})
+
+		var terrs seatbelt.TemplateErrors
+		if !errors.As(err, &terrs) {
+			t.Fatalf("expected template errors but got %+v", err)
+		}
+		if len(terrs) != 2 {
+			t.Fatalf("expected two errors but got %d: %v", len(terrs), terrs)
+		}
+		if terr := terrs[0]; terr.File != "home/about.html" || terr.Line != 2 || !strings.Contains(terr.Message, "Titel") {
+			t.Fatalf("expected missing key error at home/about.html:2 but got %s", terr)
+		}
+		if terr := terrs[1]; terr.File != "home/index.html" {
+			t.Fatalf("expected an escaping error in home/index.html but got %s", terr)
+		}
+	})
+}
@@ -6,8 +6,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bentranter/go-seatbelt"
 	"github.com/gorilla/csrf"
@@ -162,3 +165,258 @@ func TestRenderTemplateFuncs(t *testing.T) {
 		t.Fatalf("expected:\n%s\nto contain hey", rendered)
 	}
 }
+
+// writeTemplates writes the given files, keyed by their path relative to the
+// directory, to a new temporary directory.
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("%+v creating template directory", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("%+v writing template", err)
+		}
+	}
+	return dir
+}
+
+func TestRenderTemplateDiscovery(t *testing.T) {
+	t.Parallel()
+
+	dir := writeTemplates(t, map[string]string{
+		".DS_Store":                "\x00\x01",
+		"README.md":                "# Views",
+		"layouts/application.html": `<main>{{ block "main" . }}{{ end }}</main>`,
+		"layouts/application.txt":  `{{ block "main" . }}{{ end }}`,
+		"layouts/application.svg":  `<svg>{{ block "main" . }}{{ end }}</svg>`,
+		"home/index.gohtml":        `{{ define "main" }}gohtml{{ end }}`,
+		"home/about.html.tmpl":     `{{ define "main" }}{{ .Name }}{{ end }}`,
+		"home/skip.html":           `{{ define "main" }}skipped{{ end }}`,
+		"mail/welcome.txt":         `{{ define "main" }}{{ .Name }}{{ end }}`,
+		"icons/dot.svg":            `{{ define "main" }}<circle r="{{ .R }}"/>{{ end }}`,
+		"home/.draft.html":         `{{ define "main" }}{{ .Broken }{{ end }}`,
+		"layouts/application.xml":  "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<feed>{{ block \"main\" . }}{{ end }}</feed>",
+		"feeds/posts.xml":          `{{ define "main" }}{{ range . }}<entry title="{{ .Title }}">{{ .Body }}</entry>{{ end }}{{ end }}`,
+	})
+
+	r := seatbelt.NewRendererWithOption(seatbelt.RendererOption{
+		Dir:    dir,
+		Ignore: []string{"home/skip.*"},
+	})
+
+	t.Run("gohtml", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := r.HTML(buf, nil, "home/index", nil); err != nil {
+			t.Fatalf("%+v rendering gohtml template", err)
+		}
+		if output := buf.String(); output != "<main>gohtml</main>" {
+			t.Fatalf("expected gohtml template but got %#v", output)
+		}
+	})
+
+	t.Run("html.tmpl escapes html", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		if err := r.HTML(buf, nil, "home/about", map[string]string{"Name": "<b>"}); err != nil {
+			t.Fatalf("%+v rendering html.tmpl template", err)
+		}
+		if output := buf.String(); output != "<main>&lt;b&gt;</main>" {
+			t.Fatalf("expected escaped output but got %#v", output)
+		}
+	})
+
+	t.Run("ignored template", func(t *testing.T) {
+		if err := r.HTML(&bytes.Buffer{}, nil, "home/skip", nil); err == nil {
+			t.Fatalf("expected ignored template to not exist")
+		}
+	})
+
+	t.Run("text is not escaped", func(t *testing.T) {
+		output, err := r.Text("mail/welcome", map[string]string{"Name": "<b>"})
+		if err != nil {
+			t.Fatalf("%+v rendering text template", err)
+		}
+		if output != "<b>" {
+			t.Fatalf("expected unescaped output but got %#v", output)
+		}
+	})
+
+	t.Run("xml declaration", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := r.HTML(w, nil, "feeds/posts", []map[string]interface{}{
+			{"Title": `"Fish" & <Chips>`, "Body": template.HTML("<b>bold</b>")},
+		}, seatbelt.RenderOption{
+			Format: seatbelt.FormatXML,
+		}); err != nil {
+			t.Fatalf("%+v rendering xml template", err)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
+			t.Fatalf("expected application/xml but got %s", ct)
+		}
+
+		expected := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+			`<feed><entry title="&#34;Fish&#34; &amp; &lt;Chips&gt;"><b>bold</b></entry></feed>`
+		if output := w.Body.String(); output != expected {
+			t.Fatalf("expected %#v but got %#v", expected, output)
+		}
+	})
+
+	t.Run("svg content type", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := r.HTML(w, nil, "icons/dot", map[string]int{"R": 4}, seatbelt.RenderOption{
+			Format: seatbelt.FormatSVG,
+		}); err != nil {
+			t.Fatalf("%+v rendering svg template", err)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+			t.Fatalf("expected image/svg+xml but got %s", ct)
+		}
+		if output := w.Body.String(); output != `<svg><circle r="4"/></svg>` {
+			t.Fatalf("unexpected svg output %#v", output)
+		}
+	})
+}
+
+func TestRenderDuplicateTemplateNames(t *testing.T) {
+	t.Parallel()
+
+	dir := writeTemplates(t, map[string]string{
+		"layouts/application.html": `{{ block "main" . }}{{ end }}`,
+		"home/index.html":          `{{ define "main" }}html{{ end }}`,
+		"home/index.gohtml":        `{{ define "main" }}gohtml{{ end }}`,
+	})
+
+	err := seatbelt.CheckTemplates(seatbelt.RendererOption{Dir: dir})
+	if err == nil || !strings.Contains(err.Error(), "home/index.gohtml") || !strings.Contains(err.Error(), "home/index.html") {
+		t.Fatalf("expected an error naming both templates but got %v", err)
+	}
+}
+
+func TestRenderReload(t *testing.T) {
+	t.Parallel()
+
+	dir := writeTemplates(t, map[string]string{
+		"layouts/application.html": `<body>{{ block "main" . }}{{ end }}</body>`,
+		"home/index.html":          `{{ define "main" }}before{{ end }}`,
+	})
+
+	r := seatbelt.NewRenderer(dir, true)
+	defer r.Close()
+
+	// render renders the index template until it contains the expected
+	// output, since the templates are reloaded in the background.
+	render := func(t *testing.T, expected string) string {
+		t.Helper()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			buf := &bytes.Buffer{}
+			if err := r.HTML(buf, nil, "home/index", nil); err != nil {
+				t.Fatalf("%+v rendering template", err)
+			}
+			if output := buf.String(); strings.Contains(output, expected) || time.Now().After(deadline) {
+				return output
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	if output := render(t, "before"); output != "<body>before</body>" {
+		t.Fatalf("expected initial template but got %#v", output)
+	}
+
+	path := filepath.Join(dir, "home", "index.html")
+
+	if err := ioutil.WriteFile(path, []byte(`{{ define "main" }}after change{{ end }}`), 0644); err != nil {
+		t.Fatalf("%+v writing template", err)
+	}
+	if output := render(t, "after change"); output != "<body>after change</body>" {
+		t.Fatalf("expected changed template but got %#v", output)
+	}
+
+	// A template that fails to parse keeps the last good templates, and
+	// shows the error in the page.
+	if err := ioutil.WriteFile(path, []byte(`{{ define "main" }}{{ .Broken }{{ end }}`), 0644); err != nil {
+		t.Fatalf("%+v writing template", err)
+	}
+	output := render(t, "<pre")
+	if !strings.Contains(output, "after change") {
+		t.Fatalf("expected last good template in %#v", output)
+	}
+	if !strings.Contains(output, "home/index") || !strings.HasSuffix(output, "</pre></body>") {
+		t.Fatalf("expected reload error before closing body tag in %#v", output)
+	}
+}
+
+func TestRenderHTMLString(t *testing.T) {
+	t.Parallel()
+
+	r := seatbelt.NewRenderer("testdata", false, template.FuncMap{
+		"lower": strings.ToLower,
+	})
+
+	t.Run("full page", func(t *testing.T) {
+		output, err := r.HTMLString(nil, "plaintext/plain", nil)
+		if err != nil {
+			t.Fatalf("%+v rendering html template", err)
+		}
+		if !strings.HasPrefix(output, "<!DOCTYPE html>") {
+			t.Fatalf("expected layout in %#v", output)
+		}
+	})
+
+	t.Run("single block", func(t *testing.T) {
+		output, err := r.HTMLString(nil, "home/func", nil, seatbelt.RenderOption{Block: "main"})
+		if err != nil {
+			t.Fatalf("%+v rendering block", err)
+		}
+		if strings.Contains(output, "<html") {
+			t.Fatalf("expected no layout in %#v", output)
+		}
+		if !strings.Contains(output, "<h1>Func</h1>") || !strings.Contains(output, "hey") {
+			t.Fatalf("expected main block in %#v", output)
+		}
+	})
+}
+
+func TestContextRenderToString(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	ctx := seatbelt.NewTestContext(w, r)
+	ctx.AddRenderer("testdata", template.FuncMap{
+		"lower": strings.ToLower,
+	})
+
+	output, err := ctx.RenderToString("home/index", nil, seatbelt.RenderOption{Block: "main"})
+	if err != nil {
+		t.Fatalf("%+v rendering template", err)
+	}
+	if !strings.Contains(output, "HomePartial") || strings.Contains(output, "<html") {
+		t.Fatalf("expected main block without layout in %#v", output)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("expected nothing to be written to the response but got %s", w.Body.String())
+	}
+}
+
+func BenchmarkRendererHTML(b *testing.B) {
+	r := seatbelt.NewRenderer("testdata", false, template.FuncMap{
+		"lower": strings.ToLower,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := r.HTML(ioutil.Discard, req, "home/index", nil); err != nil {
+				b.Fatalf("%+v rendering template", err)
+			}
+		}
+	})
+}
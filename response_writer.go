@@ -0,0 +1,226 @@
+package seatbelt
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ErrResponseCommitted is returned when attempting to send a response after
+// the status code has already been written.
+var ErrResponseCommitted = errors.New("seatbelt: the response has already been written")
+
+// A ResponseWriter wraps an http.ResponseWriter, and keeps track of the
+// status code and size of the response, so that middleware can inspect it
+// after the handler has run.
+//
+// It implements http.Flusher and http.Hijacker only if the underlying
+// http.ResponseWriter does, so that code that checks for them, ie,
+//
+//	flusher, ok := c.Response().(http.Flusher)
+//
+// knows whether they're supported. It always implements io.ReaderFrom.
+type ResponseWriter interface {
+	http.ResponseWriter
+	io.ReaderFrom
+
+	// Status returns the status code of the response, or zero if it hasn't
+	// been written yet.
+	Status() int
+
+	// Size returns the number of bytes of the response body that have been
+	// written.
+	Size() int
+
+	// Committed returns true once the status code has been written, after
+	// which the status code and headers can no longer be changed.
+	Committed() bool
+
+	// Before registers a func that runs immediately before the status code
+	// is written, ie, in order to set a header.
+	Before(fn func())
+
+	// Unwrap returns the underlying http.ResponseWriter.
+	Unwrap() http.ResponseWriter
+}
+
+// responseWriter implements the ResponseWriter interface.
+type responseWriter struct {
+	w         http.ResponseWriter
+	status    int
+	size      int
+	committed bool
+	hijacked  bool
+	before    []func()
+}
+
+// flushWriter is a responseWriter whose underlying http.ResponseWriter is
+// an http.Flusher.
+type flushWriter struct {
+	*responseWriter
+}
+
+// hijackWriter is a responseWriter whose underlying http.ResponseWriter is
+// an http.Hijacker.
+type hijackWriter struct {
+	*responseWriter
+}
+
+// flushHijackWriter is a responseWriter whose underlying http.ResponseWriter
+// is both an http.Flusher and an http.Hijacker.
+type flushHijackWriter struct {
+	*responseWriter
+}
+
+// Flush sends any buffered data to the client.
+func (fw flushWriter) Flush() { fw.flush() }
+
+// Hijack lets the caller take over the connection.
+func (hw hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return hw.hijack() }
+
+// Flush sends any buffered data to the client.
+func (fw flushHijackWriter) Flush() { fw.flush() }
+
+// Hijack lets the caller take over the connection.
+func (fw flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return fw.hijack() }
+
+// newResponseWriter wraps the given http.ResponseWriter, unless it's
+// already a ResponseWriter.
+func newResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+
+	rw := &responseWriter{w: w}
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+
+	switch {
+	case isFlusher && isHijacker:
+		return flushHijackWriter{rw}
+	case isFlusher:
+		return flushWriter{rw}
+	case isHijacker:
+		return hijackWriter{rw}
+	}
+	return rw
+}
+
+// Header returns the response headers.
+func (rw *responseWriter) Header() http.Header {
+	return rw.w.Header()
+}
+
+// WriteHeader writes the status code, after running any funcs registered
+// with Before. Calls after the status code has been written are ignored.
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.committed {
+		return
+	}
+
+	// Mark the response as committed first, so that a Before func that
+	// writes can't run the funcs again.
+	rw.committed = true
+	rw.status = code
+
+	for _, fn := range rw.before {
+		fn()
+	}
+
+	rw.w.WriteHeader(code)
+}
+
+// Write writes to the response body, writing a 200 OK status code first if
+// one hasn't been written. Once the connection has been hijacked, it returns
+// http.ErrHijacked.
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !rw.committed {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	n, err := rw.w.Write(p)
+	rw.size += n
+	return n, err
+}
+
+// ReadFrom copies from the given reader to the response body, using the
+// underlying http.ResponseWriter's ReadFrom if it has one.
+func (rw *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rw.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !rw.committed {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if rf, ok := rw.w.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(r)
+		rw.size += int(n)
+		return n, err
+	}
+
+	// Hide our own ReadFrom method from io.Copy, which would otherwise call
+	// it recursively.
+	return io.Copy(struct{ io.Writer }{rw}, r)
+}
+
+// flush writes a 200 OK status code if one hasn't been written, and then
+// flushes the underlying http.ResponseWriter, which must be an
+// http.Flusher.
+func (rw *responseWriter) flush() {
+	if rw.hijacked {
+		return
+	}
+	if !rw.committed {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.w.(http.Flusher).Flush()
+}
+
+// hijack takes over the connection of the underlying http.ResponseWriter,
+// which must be an http.Hijacker.
+func (rw *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := rw.w.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Nothing else can be written once the connection has been taken over.
+	rw.committed = true
+	rw.hijacked = true
+	rw.status = http.StatusSwitchingProtocols
+	return conn, buf, nil
+}
+
+// Status returns the status code of the response, or zero if it hasn't been
+// written yet.
+func (rw *responseWriter) Status() int {
+	return rw.status
+}
+
+// Size returns the number of bytes of the response body that have been
+// written.
+func (rw *responseWriter) Size() int {
+	return rw.size
+}
+
+// Committed returns true once the status code has been written.
+func (rw *responseWriter) Committed() bool {
+	return rw.committed
+}
+
+// Before registers a func that runs immediately before the status code is
+// written.
+func (rw *responseWriter) Before(fn func()) {
+	rw.before = append(rw.before, fn)
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.w
+}
@@ -0,0 +1,138 @@
+package seatbelt_test
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestResponseWriter(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New()
+
+	// statuses receives the status and size of each response, as seen by
+	// the middleware.
+	type result struct {
+		status, size int
+	}
+	results := make(chan result, 1)
+
+	app.Use(func(next func(seatbelt.Context) error) func(seatbelt.Context) error {
+		return func(c seatbelt.Context) error {
+			c.Response().Before(func() {
+				c.Response().Header().Set("X-Before", "true")
+			})
+
+			err := next(c)
+			results <- result{c.Response().Status(), c.Response().Size()}
+			return err
+		}
+	})
+
+	app.Get("/", func(c seatbelt.Context) error {
+		return c.String(202, "accepted")
+	})
+	app.Get("/partial", func(c seatbelt.Context) error {
+		c.String(200, "partial")
+		return seatbelt.NewHTTPError(http.StatusInternalServerError)
+	})
+	app.Get("/stream", func(c seatbelt.Context) error {
+		flusher, ok := c.Response().(http.Flusher)
+		if !ok {
+			t.Errorf("expected the response writer to be a flusher")
+		}
+
+		c.Response().Write([]byte("data: one\n\n"))
+		flusher.Flush()
+		_, err := c.Response().ReadFrom(strings.NewReader("data: two\n\n"))
+		return err
+	})
+	app.Get("/hijack", func(c seatbelt.Context) error {
+		conn, buf, err := c.Response().(http.Hijacker).Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		return buf.Flush()
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	cases := []struct {
+		path   string
+		code   int
+		body   string
+		status int
+	}{
+		{path: "/", code: 202, body: "accepted", status: 202},
+		{path: "/partial", code: 200, body: "partial", status: 200},
+		{path: "/stream", code: 200, body: "data: one\n\ndata: two\n\n", status: 200},
+		{path: "/hijack", code: 200, body: "hijacked", status: http.StatusSwitchingProtocols},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatalf("%+v executing http request", err)
+			}
+			body, err := ioutil.ReadAll(bufio.NewReader(resp.Body))
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("%+v reading body", err)
+			}
+
+			if resp.StatusCode != tc.code {
+				t.Fatalf("expected %d but got %d", tc.code, resp.StatusCode)
+			}
+
+			// The error handler must not write to a response that's already
+			// been committed.
+			if string(body) != tc.body {
+				t.Fatalf("expected body %q but got %q", tc.body, body)
+			}
+
+			res := <-results
+			if res.status != tc.status {
+				t.Fatalf("expected middleware to see status %d but got %d", tc.status, res.status)
+			}
+			if tc.path != "/hijack" {
+				if res.size != len(tc.body) {
+					t.Fatalf("expected middleware to see size %d but got %d", len(tc.body), res.size)
+				}
+				if resp.Header.Get("X-Before") != "true" {
+					t.Fatalf("expected Before func to set header")
+				}
+			}
+		})
+	}
+}
+
+func TestResponseWriterWithoutOptionalInterfaces(t *testing.T) {
+	w := struct{ http.ResponseWriter }{httptest.NewRecorder()}
+	c := seatbelt.NewTestContext(w, httptest.NewRequest("GET", "/", nil))
+
+	if _, ok := c.Response().(http.Hijacker); ok {
+		t.Fatalf("expected the response writer to not be a hijacker")
+	}
+	if _, ok := c.Response().(http.Flusher); ok {
+		t.Fatalf("expected the response writer to not be a flusher")
+	}
+	if _, err := c.SSE(); err == nil {
+		t.Fatalf("expected an event stream to fail when flushing is unsupported")
+	}
+
+	c.String(200, "sent")
+	if err := c.Redirect("/"); err != seatbelt.ErrResponseCommitted {
+		t.Fatalf("expected ErrResponseCommitted redirecting after the response was sent but got %v", err)
+	}
+}
@@ -3,11 +3,14 @@ package seatbelt
 import (
 	"encoding/gob"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/gorilla/csrf"
@@ -36,6 +39,20 @@ type App struct {
 	signingKey   []byte
 	middlewares  []MiddlewareFunc
 	errorHandler func(c Context, err error)
+	liveReload   *liveReloader
+	globals      map[string]interface{}
+
+	websocketOrigins []string
+	sensitiveFields  []string
+	validators       map[string]ValidatorFunc
+	decoders         map[reflect.Type]DecoderFunc
+	maxMemory        int64
+	maxUploadSize    int64
+	maxBodySize      int64
+
+	disallowUnknownFields   bool
+	rejectUnpermittedParams bool
+	logUnpermittedParams    bool
 }
 
 // MiddlewareFunc is the type alias for Seatbelt middleware.
@@ -45,8 +62,78 @@ type MiddlewareFunc func(fn func(ctx Context) error) func(Context) error
 type Option struct {
 	TemplateDir string           // The directory where the templates reside.
 	SigningKey  string           // The signing key for the cookie session store.
-	Reload      bool             // Whether or not to reload templates on each request.
+	Reload      bool             // Whether or not to reload templates when they change.
 	Funcs       template.FuncMap // HTML functions.
+
+	// TemplateFormats maps additional template file extensions to the
+	// format they are parsed as, ie, {".gohtml": seatbelt.FormatHTML}.
+	TemplateFormats map[string]Format
+
+	// TemplateIgnore is a list of glob patterns for files in the template
+	// directory that should not be parsed. Hidden files are always ignored.
+	TemplateIgnore []string
+
+	// Timeout, if set, is the duration after which every request is
+	// cancelled, and a 503 Service Unavailable is sent. See the Timeout
+	// middleware for details.
+	Timeout time.Duration
+
+	// LiveReload, if true, reloads the page in the browser whenever a
+	// template, or a file in a directory served by FileServer, changes. It
+	// has no effect unless Reload is also true, so that it's never enabled
+	// outside of development.
+	LiveReload bool
+
+	// WebSocketOrigins is a list of origins, other than the application's
+	// own, that are allowed to open websocket connections, ie,
+	// "https://admin.example.com".
+	WebSocketOrigins []string
+
+	// SensitiveFields is a list of form field names whose values are never
+	// kept to refill a form after it fails validation, ie, "card_number".
+	// Passwords, the CSRF token, and uploaded files are never kept either.
+	SensitiveFields []string
+
+	// ETag, if true, sends a weak ETag with every successful HTML and JSON
+	// response, and responds with a 304 Not Modified when it matches the
+	// request's If-None-Match header. The response is still rendered, so
+	// use Context.Fresh to skip expensive work entirely.
+	//
+	// The ETag is computed from the rendered output, so a page that
+	// includes something that changes on every request, ie, the csrf or
+	// csrfField template funcs, never matches. Use Context.Fresh with a
+	// version of the page's data for those pages instead.
+	ETag bool
+
+	// MaxMemory is the maximum number of bytes of a multipart form that are
+	// held in memory, after which uploaded files are stored in temporary
+	// files on disk. The default is 32MB.
+	MaxMemory int64
+
+	// MaxUploadSize is the maximum size, in bytes, of a form's body. Larger
+	// requests are rejected with a 413 Request Entity Too Large. The default
+	// is 32MB, and a negative value removes the limit.
+	MaxUploadSize int64
+
+	// MaxBodySize is the maximum size, in bytes, of a JSON request body
+	// decoded by Params. Larger requests are rejected with a 413 Request
+	// Entity Too Large. The default is 1MB.
+	MaxBodySize int64
+
+	// DisallowUnknownFields, if true, rejects JSON request bodies that
+	// contain fields that the struct they're decoded into doesn't have.
+	DisallowUnknownFields bool
+
+	// RejectUnpermittedParams, if true, rejects requests with params that
+	// Params may not assign, either because their field is tagged
+	// params:"-", or because they aren't permitted by the Permit option.
+	// By default, those params are ignored.
+	RejectUnpermittedParams bool
+
+	// LogUnpermittedParams, if true, logs a warning for each request with
+	// params that Params ignores, which is useful for finding forms that
+	// send fields that are missing from their Permit option.
+	LogUnpermittedParams bool
 }
 
 // setDefaults sets the default values for Seatbelt options.
@@ -108,12 +195,39 @@ func New(opts ...Option) *App {
 	mux := chi.NewRouter()
 	mux.Use(csrf.Protect(signingKey))
 
-	return &App{
-		mux:        chi.NewRouter(),
-		store:      cookieStore,
-		render:     NewRenderer(opt.TemplateDir, opt.Reload, opt.Funcs),
-		signingKey: signingKey,
+	app := &App{
+		mux:   chi.NewRouter(),
+		store: cookieStore,
+		render: NewRendererWithOption(RendererOption{
+			Dir:     opt.TemplateDir,
+			Reload:  opt.Reload,
+			Funcs:   opt.Funcs,
+			Formats: opt.TemplateFormats,
+			Ignore:  opt.TemplateIgnore,
+			ETag:    opt.ETag,
+		}),
+		signingKey:       signingKey,
+		websocketOrigins: opt.WebSocketOrigins,
+		sensitiveFields:  opt.SensitiveFields,
+		maxMemory:        opt.MaxMemory,
+		maxUploadSize:    opt.MaxUploadSize,
+		maxBodySize:      opt.MaxBodySize,
+
+		disallowUnknownFields:   opt.DisallowUnknownFields,
+		rejectUnpermittedParams: opt.RejectUnpermittedParams,
+		logUnpermittedParams:    opt.LogUnpermittedParams,
+	}
+
+	if opt.Timeout > 0 {
+		app.Use(Timeout(opt.Timeout))
+	}
+
+	if opt.LiveReload && opt.Reload {
+		app.liveReload = newLiveReloader(app.render)
+		app.mux.Use(app.liveReload.middleware)
 	}
+
+	return app
 }
 
 // Start is a convenience method for starting the application server with a
@@ -126,9 +240,21 @@ func New(opts ...Option) *App {
 // Production applications should create their own
 // *http.Server, and pass the *seatbelt.App to that *http.Server's `Handler`.
 func (a *App) Start(addr string) error {
+	defer a.Close()
 	return http.ListenAndServe(addr, a)
 }
 
+// Close stops watching the template directory, and the directories served
+// by FileServer, for changes, which is only done when the Reload option is
+// set. It should be called once the application has stopped serving
+// requests, ie, after its *http.Server has been shut down.
+func (a *App) Close() error {
+	if a.liveReload != nil {
+		a.liveReload.close()
+	}
+	return a.render.Close()
+}
+
 // UseStd registers standard HTTP middleware on the application.
 func (a *App) UseStd(middleware ...func(http.Handler) http.Handler) {
 	a.mux.Use(middleware...)
@@ -139,6 +265,18 @@ func (a *App) Use(middleware ...MiddlewareFunc) {
 	a.middlewares = append(a.middlewares, middleware...)
 }
 
+// SetGlobal sets a value that's merged into the data of every template the
+// application renders, ie, the application's name.
+//
+// SetGlobal is not safe to call concurrently with requests, so globals
+// should be set before the application starts.
+func (a *App) SetGlobal(key string, value interface{}) {
+	if a.globals == nil {
+		a.globals = make(map[string]interface{})
+	}
+	a.globals[key] = value
+}
+
 // SetErrorHandler allows you to set a custom error handler that runs when an
 // error is returned from an HTTP handler.
 func (a *App) SetErrorHandler(fn func(c Context, err error)) {
@@ -156,19 +294,59 @@ func (a *App) ErrorHandler(c Context, err error) {
 
 	fmt.Printf("hit error handler: %#v\n", err)
 
+	// If the handler has already written a response, ie, it failed part way
+	// through streaming one, there's no way to send another.
+	if c.Response().Committed() {
+		return
+	}
+
+	code := http.StatusInternalServerError
+	var herr *HTTPError
+	var verrs ValidationErrors
+	switch {
+	case errors.As(err, &herr):
+		code = herr.Code
+	case errors.As(err, &verrs):
+		code = http.StatusUnprocessableEntity
+	}
+
+	// A timeout isn't a problem with the user's input, so it's never
+	// redirected back to the form like other errors from a POST.
+	if errors.Is(err, ErrTimeout) {
+		c.String(code, err.Error())
+		return
+	}
+
 	switch c.Request().Method {
 	case "GET", "HEAD", "OPTIONS":
-		c.String(http.StatusInternalServerError, err.Error())
+		c.String(code, err.Error())
 	default:
+		// Keep the user's input and the problems with each field, so that
+		// the form can show them after the redirect.
+		if verrs != nil {
+			if b, ok := c.(interface{ base() *context }); ok {
+				b.base().flashForm(verrs)
+			}
+		}
+
 		from := c.Request().Referer()
 		c.Session().Flash("alert", err.Error())
 		c.Redirect(from)
 	}
 }
 
+// A routeOption configures how the requests to a route are served.
+type routeOption struct {
+	// stream, if true, means the route's response is streamed for as long
+	// as the connection is open, so it's never timed out.
+	stream bool
+}
+
 // serveContext creates and registers a Seatbelt handler for an HTTP request.
-func (a *App) serveContext(w http.ResponseWriter, r *http.Request, handle func(c Context) error) {
-	c := &context{w: w, r: r, store: a.store, render: a.render}
+func (a *App) serveContext(w http.ResponseWriter, r *http.Request, handle func(c Context) error, opt routeOption) {
+	r, values := withValues(r)
+	c := &context{w: newResponseWriter(w), r: r, store: a.store, render: a.render, app: a, values: values, forms: &multipartForms{}, stream: opt.stream}
+	defer c.forms.removeAll()
 
 	// Iterate over the middleware in reverse order, so that the order
 	// in which middleware is registered suggests that it is run from
@@ -183,54 +361,111 @@ func (a *App) serveContext(w http.ResponseWriter, r *http.Request, handle func(c
 		handle = a.middlewares[i](handle)
 	}
 
-	// Add a default template method for accessing all of the flash messages
-	// in order to make it easier to render them from any template.
-	c.render.funcs["flashes"] = func() map[string]interface{} {
-		return c.Session().Flashes()
-	}
-
 	if err := handle(c); err != nil {
 		a.ErrorHandler(c, err)
 	}
 }
 
+// routeConstraints are the names of the constraints that can be used in
+// route patterns in place of a regular expression, ie, /products/{id:int},
+// along with the regular expressions they stand for. Requests with path
+// params that don't match their constraint are 404'd without calling the
+// handler.
+var routeConstraints = map[string]string{
+	"int":  `[0-9]+`,
+	"slug": `[a-z0-9]+(?:-[a-z0-9]+)*`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// expandRoutePattern replaces the named constraints in the given route
+// pattern with their regular expressions, so that chi can match them.
+func expandRoutePattern(pattern string) string {
+	var b strings.Builder
+
+	for {
+		start := strings.IndexByte(pattern, '{')
+		if start < 0 {
+			break
+		}
+
+		// Find the closing brace, skipping over any braces in a regular
+		// expression, as chi does.
+		end, depth := -1, 0
+		for i := start; i < len(pattern) && end < 0; i++ {
+			switch pattern[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+
+		param := pattern[start+1 : end]
+		if i := strings.IndexByte(param, ':'); i >= 0 {
+			if expr, ok := routeConstraints[param[i+1:]]; ok {
+				param = param[:i+1] + expr
+			}
+		}
+
+		b.WriteString(pattern[:start])
+		b.WriteString("{" + param + "}")
+		pattern = pattern[end+1:]
+	}
+
+	b.WriteString(pattern)
+	return b.String()
+}
+
 // handle registers the given handler to handle requests at the given path
-// with the given HTTP verb.
-func (a *App) handle(verb, path string, handle func(c Context) error) {
+// with the given HTTP verb. The path may use any of the routeConstraints.
+func (a *App) handle(verb, path string, handle func(c Context) error, opts ...routeOption) {
+	path = expandRoutePattern(path)
+
+	var opt routeOption
+	for _, o := range opts {
+		opt = o
+	}
+
 	switch verb {
 	case "HEAD":
 		a.mux.Head(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a.serveContext(w, r, handle)
+			a.serveContext(w, r, handle, opt)
 		}))
 
 	case "OPTIONS":
 		a.mux.Options(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a.serveContext(w, r, handle)
+			a.serveContext(w, r, handle, opt)
 		}))
 
 	case "GET":
 		a.mux.Get(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a.serveContext(w, r, handle)
+			a.serveContext(w, r, handle, opt)
 		}))
 
 	case "POST":
 		a.mux.Post(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a.serveContext(w, r, handle)
+			a.serveContext(w, r, handle, opt)
 		}))
 
 	case "PUT":
 		a.mux.Put(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a.serveContext(w, r, handle)
+			a.serveContext(w, r, handle, opt)
 		}))
 
 	case "PATCH":
 		a.mux.Patch(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a.serveContext(w, r, handle)
+			a.serveContext(w, r, handle, opt)
 		}))
 
 	case "DELETE":
 		a.mux.Delete(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			a.serveContext(w, r, handle)
+			a.serveContext(w, r, handle, opt)
 		}))
 
 	default:
@@ -249,6 +484,15 @@ func (a *App) Options(path string, handle func(c Context) error) {
 }
 
 // Get routes GET requests to the given path.
+//
+// Path params can be constrained to integers, slugs, or UUIDs, or a regular
+// expression, so that requests with invalid params are 404'd before the
+// handler is called, ie,
+//
+//	app.Get("/products/{id:int}", showProduct)
+//	app.Get("/posts/{slug:slug}", showPost)
+//	app.Get("/orders/{uuid:uuid}", showOrder)
+//	app.Get("/years/{year:[0-9]{4}}", showYear)
 func (a *App) Get(path string, handle func(c Context) error) {
 	a.handle("GET", path, handle)
 }
@@ -281,6 +525,10 @@ func (a *App) FileServer(path string, dir string) {
 
 	fs := http.StripPrefix(path, http.FileServer(http.Dir(dir)))
 
+	if a.liveReload != nil {
+		a.liveReload.watch(dir)
+	}
+
 	if path != "/" && path[len(path)-1] != '/' {
 		a.mux.Get(path, http.RedirectHandler(path+"/", 301).ServeHTTP)
 		path += "/"
@@ -295,5 +543,8 @@ func (a *App) FileServer(path string, dir string) {
 // ServeHTTP makes the Seatbelt application implement the http.Handler
 // interface.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Attach the values before any middleware runs, so that standard
+	// library middleware can share values with SetValue.
+	r, _ = withValues(a.stripFormat(r))
 	a.mux.ServeHTTP(w, r)
 }
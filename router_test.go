@@ -53,3 +53,44 @@ func TestRouter(t *testing.T) {
 		})
 	}
 }
+
+func TestRouteConstraints(t *testing.T) {
+	app := seatbelt.New()
+	app.Get("/products/{id:int}", func(c seatbelt.Context) error {
+		return c.String(http.StatusOK, c.PathParam("id"))
+	})
+	app.Get("/posts/{slug:slug}", func(c seatbelt.Context) error {
+		return c.String(http.StatusOK, c.PathParam("slug"))
+	})
+	app.Get("/orders/{uuid:uuid}", func(c seatbelt.Context) error {
+		return c.String(http.StatusOK, c.PathParam("uuid"))
+	})
+	app.Get("/years/{year:[0-9]{4}}", func(c seatbelt.Context) error {
+		return c.String(http.StatusOK, c.PathParam("year"))
+	})
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{path: "/products/42", code: http.StatusOK},
+		{path: "/products/abc", code: http.StatusNotFound},
+		{path: "/products/-1", code: http.StatusNotFound},
+		{path: "/posts/hello-world", code: http.StatusOK},
+		{path: "/posts/Hello_World", code: http.StatusNotFound},
+		{path: "/posts/trailing-", code: http.StatusNotFound},
+		{path: "/orders/123e4567-e89b-12d3-a456-426614174000", code: http.StatusOK},
+		{path: "/orders/123e4567", code: http.StatusNotFound},
+		{path: "/years/2021", code: http.StatusOK},
+		{path: "/years/21", code: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		if w.Code != tc.code {
+			t.Fatalf("expected HTTP %d for %s but got %d", tc.code, tc.path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package seatbelt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultHeartbeat is how often an event stream sends a comment to keep the
+// connection open while it waits for events.
+const defaultHeartbeat = 15 * time.Second
+
+// An Event is a single Server-Sent Event.
+type Event struct {
+	// The Name of the event, which is dispatched to listeners registered
+	// with addEventListener. If empty, the browser dispatches a "message"
+	// event.
+	Name string
+
+	// The ID of the event, which the browser sends back in the
+	// Last-Event-ID header when it reconnects.
+	ID string
+
+	// The Data of the event. Strings and byte slices are sent as-is, and
+	// anything else is encoded as JSON.
+	Data interface{}
+}
+
+// An EventStream sends Server-Sent Events to the client. Each event is
+// flushed as soon as it's sent.
+type EventStream struct {
+	c         *context
+	flusher   http.Flusher
+	heartbeat time.Duration
+
+	mu sync.Mutex
+}
+
+// SSE starts a stream of Server-Sent Events.
+//
+//	func notifications(c seatbelt.Context) error {
+//		stream, err := c.SSE()
+//		if err != nil {
+//			return err
+//		}
+//
+//		sub := broker.Subscribe("notifications")
+//		defer sub.Close()
+//
+//		return stream.Listen(sub.Events())
+//	}
+//
+// A route that streams events should be registered with App.SSE, rather
+// than calling SSE itself, so that it's never buffered or cut off by the
+// Timeout middleware.
+func (c *context) SSE() (*EventStream, error) {
+	if c.w.Committed() {
+		return nil, ErrResponseCommitted
+	}
+	flusher, ok := c.w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("seatbelt: the response writer does not support streaming")
+	}
+
+	h := c.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	c.w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventStream{c: c, flusher: flusher, heartbeat: defaultHeartbeat}, nil
+}
+
+// SSE routes GET requests to the given path to a handler that streams
+// Server-Sent Events, ie,
+//
+//	app.SSE("/notifications", func(c seatbelt.Context, stream *seatbelt.EventStream) error {
+//		sub := broker.Subscribe("notifications")
+//		defer sub.Close()
+//
+//		return stream.Listen(sub.Events())
+//	})
+//
+// Unlike routes that call c.SSE themselves, the route is never timed out by
+// the Timeout middleware, since the stream stays open until the client
+// disconnects.
+func (a *App) SSE(path string, handle func(c Context, stream *EventStream) error) {
+	a.handle("GET", path, func(c Context) error {
+		stream, err := c.SSE()
+		if err != nil {
+			return err
+		}
+		return handle(c, stream)
+	}, routeOption{stream: true})
+}
+
+// LastEventID returns the ID of the last event the client received before
+// it reconnected, or an empty string if this is its first connection.
+func (s *EventStream) LastEventID() string {
+	return s.c.r.Header.Get("Last-Event-ID")
+}
+
+// Done returns a channel that's closed once the client disconnects.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.c.Done()
+}
+
+// Heartbeat sets how often Listen sends a comment to keep the connection
+// open while no events are being sent. A duration of zero disables the
+// heartbeat. The default is 15 seconds.
+func (s *EventStream) Heartbeat(d time.Duration) {
+	s.heartbeat = d
+}
+
+// Send sends an event with the given name, ID, and data. Either the name or
+// the ID may be empty.
+func (s *EventStream) Send(event, id string, data interface{}) error {
+	var payload string
+	switch d := data.(type) {
+	case string:
+		payload = d
+	case []byte:
+		payload = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + sanitizeEventField(id) + "\n")
+	}
+	if event != "" {
+		b.WriteString("event: " + sanitizeEventField(event) + "\n")
+	}
+
+	// Data containing newlines is sent as multiple data fields, which the
+	// browser joins back together.
+	payload = strings.ReplaceAll(payload, "\r\n", "\n")
+	for _, line := range strings.Split(payload, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Retry tells the client how long to wait before reconnecting if the
+// connection is lost.
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.write("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n")
+}
+
+// Comment sends a comment, which the client ignores.
+func (s *EventStream) Comment(text string) error {
+	return s.write(": " + sanitizeEventField(text) + "\n\n")
+}
+
+// Listen sends each event received from the given channel until either the
+// channel is closed, or the client disconnects, sending heartbeat comments
+// in between.
+func (s *EventStream) Listen(events <-chan Event) error {
+	var tick <-chan time.Time
+	if s.heartbeat > 0 {
+		ticker := time.NewTicker(s.heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.Done():
+			return nil
+
+		case <-tick:
+			if err := s.Comment("heartbeat"); err != nil {
+				return nil
+			}
+
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(e.Name, e.ID, e.Data); err != nil {
+				if s.c.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// write writes and flushes the given raw event stream data, returning an
+// error if the client has disconnected.
+func (s *EventStream) write(data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.c.Err(); err != nil {
+		return err
+	}
+	if _, err := s.c.w.Write([]byte(data)); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// sanitizeEventField removes newlines from a single line field, since they
+// would otherwise end the field early.
+func sanitizeEventField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// subscriptionBuffer is the number of events a Subscription holds before
+// newer events are dropped.
+const subscriptionBuffer = 16
+
+// A Broker broadcasts events to the subscribers of a topic, within a single
+// process.
+type Broker struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+}
+
+// NewBroker returns a new Broker.
+func NewBroker() *Broker {
+	return &Broker{
+		topics: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// A Subscription receives the events published to the topics it's
+// subscribed to.
+type Subscription struct {
+	broker *Broker
+	topics []string
+	events chan Event
+	once   sync.Once
+}
+
+// Subscribe returns a Subscription to the given topics. The subscription
+// must be closed once it's no longer needed.
+func (b *Broker) Subscribe(topics ...string) *Subscription {
+	sub := &Subscription{
+		broker: b,
+		topics: topics,
+		events: make(chan Event, subscriptionBuffer),
+	}
+
+	b.mu.Lock()
+	for _, topic := range topics {
+		if b.topics[topic] == nil {
+			b.topics[topic] = make(map[*Subscription]struct{})
+		}
+		b.topics[topic][sub] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Publish sends the given event to every subscriber of the given topic.
+//
+// Publish never blocks: if a subscriber isn't keeping up, the event is
+// dropped for that subscriber.
+func (b *Broker) Publish(topic string, e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.topics[topic] {
+		select {
+		case sub.events <- e:
+		default:
+			log.Warn().Str("topic", topic).Msg("dropped event for a slow subscriber")
+		}
+	}
+}
+
+// Events returns the channel that receives the subscription's events. The
+// channel is closed when the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unsubscribes from every topic.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		for _, topic := range s.topics {
+			delete(s.broker.topics[topic], s)
+			if len(s.broker.topics[topic]) == 0 {
+				delete(s.broker.topics, topic)
+			}
+		}
+		s.broker.mu.Unlock()
+
+		close(s.events)
+	})
+}
@@ -0,0 +1,157 @@
+package seatbelt_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+// readEvent reads lines from an event stream up to the next blank line.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("%+v reading event stream", err)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestContextSSE(t *testing.T) {
+	broker := seatbelt.NewBroker()
+	subscribed := make(chan struct{})
+	finished := make(chan error, 1)
+
+	app := seatbelt.New()
+	app.Get("/events", func(c seatbelt.Context) error {
+		stream, err := c.SSE()
+		if err != nil {
+			return err
+		}
+		stream.Heartbeat(10 * time.Millisecond)
+
+		if err := stream.Retry(3 * time.Second); err != nil {
+			return err
+		}
+		if err := stream.Send("resume", "", stream.LastEventID()); err != nil {
+			return err
+		}
+
+		sub := broker.Subscribe("notifications")
+		defer sub.Close()
+		close(subscribed)
+
+		err = stream.Listen(sub.Events())
+		finished <- err
+		return err
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL+"/events", nil)
+	if err != nil {
+		t.Fatalf("%+v creating request", err)
+	}
+	req.Header.Set("Last-Event-ID", "41")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%+v connecting to event stream", err)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected content type text/event-stream but got %s", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+
+	if event := readEvent(t, r); event != "retry: 3000\n" {
+		t.Fatalf("expected retry hint but got %q", event)
+	}
+	if event := readEvent(t, r); event != "event: resume\ndata: 41\n" {
+		t.Fatalf("expected last event id to be sent but got %q", event)
+	}
+
+	<-subscribed
+	broker.Publish("notifications", seatbelt.Event{
+		Name: "message",
+		ID:   "42",
+		Data: map[string]string{"text": "hello"},
+	})
+	broker.Publish("other", seatbelt.Event{Data: "not subscribed"})
+
+	// Skip any heartbeats sent before the event arrives.
+	event := readEvent(t, r)
+	for event == ": heartbeat\n" {
+		event = readEvent(t, r)
+	}
+	expected := "id: 42\nevent: message\ndata: {\"text\":\"hello\"}\n"
+	if event != expected {
+		t.Fatalf("expected event %q but got %q", expected, event)
+	}
+
+	resp.Body.Close()
+
+	select {
+	case err := <-finished:
+		if err != nil {
+			t.Fatalf("%+v returned after client disconnected", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected handler to return after client disconnected")
+	}
+}
+
+func TestEventStreamMultilineData(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	c := seatbelt.NewTestContext(w, r, nil)
+
+	stream, err := c.SSE()
+	if err != nil {
+		t.Fatalf("%+v starting event stream", err)
+	}
+	if err := stream.Send("", "", "line one\nline two"); err != nil {
+		t.Fatalf("%+v sending event", err)
+	}
+
+	expected := "data: line one\ndata: line two\n\n"
+	if body := c.ResponseRecorder.Body.String(); body != expected {
+		t.Fatalf("expected body %q but got %q", expected, body)
+	}
+	if !c.ResponseRecorder.Flushed {
+		t.Fatal("expected event stream to be flushed")
+	}
+}
+
+func TestBrokerSubscriptionClose(t *testing.T) {
+	broker := seatbelt.NewBroker()
+
+	sub := broker.Subscribe("a", "b")
+	broker.Publish("b", seatbelt.Event{Data: "first"})
+	sub.Close()
+
+	// Publishing after the subscription is closed must not panic.
+	broker.Publish("b", seatbelt.Event{Data: "second"})
+
+	var received []interface{}
+	for e := range sub.Events() {
+		received = append(received, e.Data)
+	}
+	if len(received) != 1 || received[0] != "first" {
+		t.Fatalf("expected only the first event but got %v", received)
+	}
+}
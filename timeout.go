@@ -0,0 +1,220 @@
+package seatbelt
+
+import (
+	"bytes"
+	sctx "context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Timeout returns middleware that cancels the request's context after the
+// given duration.
+//
+// If the handler hasn't returned by then, an HTTPError wrapping ErrTimeout
+// is passed to the error handler, which by default responds with a 503
+// Service Unavailable, and anything the handler writes afterwards is
+// discarded. Handlers should watch c.Done() in order to stop any work once
+// the timeout has fired.
+//
+// The handler runs in its own goroutine, with a copy of the context. Values
+// it sets with Set are shared with the original context, so middleware that
+// runs before Timeout can still read them. If the handler panics, the panic
+// is re-raised as a *PanicError, with the stack trace of the handler's
+// goroutine.
+//
+// The handler's response is buffered until it returns, so the timeout
+// should not be used for streaming responses. Routes registered with
+// WebSocket or SSE are never timed out.
+//
+// To apply a timeout to every route, use the Timeout option, or
+//
+//	app.Use(seatbelt.Timeout(5 * time.Second))
+//
+// Or, to apply a timeout to a single route,
+//
+//	app.Get("/reports", seatbelt.Timeout(30*time.Second)(reports))
+func Timeout(d time.Duration) MiddlewareFunc {
+	return func(next func(Context) error) func(Context) error {
+		return func(c Context) error {
+			b, ok := c.(interface{ base() *context })
+			if !ok || b.base().stream {
+				return next(c)
+			}
+
+			tw := &timeoutWriter{h: make(http.Header)}
+
+			// Mark the writer as timed out before cancelling the context,
+			// so that the handler can never observe the cancellation while
+			// its writes are still accepted.
+			parent, cancel := sctx.WithCancel(c.Request().Context())
+			defer cancel()
+
+			ctx := &timeoutContext{Context: parent, deadline: time.Now().Add(d), tw: tw}
+			timer := time.AfterFunc(d, func() {
+				tw.mu.Lock()
+				tw.timedOut = true
+				tw.mu.Unlock()
+				cancel()
+			})
+			defer timer.Stop()
+
+			// Run the handler with a copy of the context that writes to a
+			// buffer, so that nothing it writes reaches the client if the
+			// timeout fires first.
+			tc := b.base().WithRequest(c.Request().WithContext(ctx)).(*context)
+			tc.w = newResponseWriter(tw)
+
+			done := make(chan error, 1)
+			panicked := make(chan *PanicError, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						// The stack has to be captured here, since it's
+						// lost once the panic is re-raised in the
+						// request's goroutine.
+						panicked <- &PanicError{Value: p, Stack: debug.Stack()}
+					}
+				}()
+				done <- next(tc)
+			}()
+
+			select {
+			case p := <-panicked:
+				if p.Value == http.ErrAbortHandler {
+					panic(p.Value)
+				}
+				panic(p)
+
+			case err := <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				if tw.timedOut {
+					return timeoutError()
+				}
+
+				w := c.Response()
+				for key, vals := range tw.h {
+					w.Header()[key] = vals
+				}
+				if tw.wroteHeader || tw.buf.Len() > 0 {
+					w.WriteHeader(tw.code)
+					w.Write(tw.buf.Bytes())
+				}
+				return err
+
+			case <-ctx.Done():
+				if ctx.Err() == sctx.DeadlineExceeded {
+					return timeoutError()
+				}
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// timeoutError returns the error passed to the error handler when a handler
+// times out. A new one is returned each time, so that one request's error
+// can't be changed by another.
+func timeoutError() error {
+	return &HTTPError{
+		Code:    http.StatusServiceUnavailable,
+		Message: ErrTimeout.Error(),
+		Err:     ErrTimeout,
+	}
+}
+
+// A PanicError is a panic recovered from a handler that ran in another
+// goroutine, ie, because of a timeout, along with the stack trace of that
+// goroutine.
+type PanicError struct {
+	Value interface{} // The value the handler panicked with.
+	Stack []byte      // The stack trace of the handler's goroutine.
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", e.Value, e.Stack)
+}
+
+// A timeoutContext is cancelled when its timeout fires, or when its parent
+// is cancelled.
+type timeoutContext struct {
+	sctx.Context
+	deadline time.Time
+	tw       *timeoutWriter
+}
+
+// Deadline returns the time the timeout fires, or the parent's deadline if
+// it's earlier.
+func (ctx *timeoutContext) Deadline() (time.Time, bool) {
+	if deadline, ok := ctx.Context.Deadline(); ok && deadline.Before(ctx.deadline) {
+		return deadline, true
+	}
+	return ctx.deadline, true
+}
+
+// Err returns context.DeadlineExceeded if the timeout has fired, or the
+// parent's error if it was cancelled.
+func (ctx *timeoutContext) Err() error {
+	err := ctx.Context.Err()
+	if err == nil {
+		return nil
+	}
+
+	ctx.tw.mu.Lock()
+	defer ctx.tw.mu.Unlock()
+	if ctx.tw.timedOut {
+		return sctx.DeadlineExceeded
+	}
+	return err
+}
+
+// A timeoutWriter buffers a handler's response until it returns, and
+// discards anything written after the timeout has fired.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	h           http.Header
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header returns the buffered response headers.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// Write writes to the buffer, unless the timeout has fired.
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+	return tw.buf.Write(p)
+}
+
+// WriteHeader records the status code, unless the timeout has fired.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+func (tw *timeoutWriter) writeHeader(code int) {
+	tw.wroteHeader = true
+	tw.code = code
+}
@@ -0,0 +1,166 @@
+package seatbelt_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	app := seatbelt.New(seatbelt.Option{Timeout: 50 * time.Millisecond})
+
+	// writeErr receives the error from the slow handler's write after the
+	// timeout has fired.
+	writeErr := make(chan error, 1)
+
+	app.Get("/slow", func(c seatbelt.Context) error {
+		<-c.Done()
+		writeErr <- c.String(200, "too late")
+		return c.Err()
+	})
+	app.Get("/fast", func(c seatbelt.Context) error {
+		if _, ok := c.Deadline(); !ok {
+			t.Errorf("expected the context to have a deadline")
+		}
+		c.Response().Header().Set("X-Fast", "true")
+		return c.String(201, "fast")
+	})
+	app.Get("/route", seatbelt.Timeout(time.Millisecond)(func(c seatbelt.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return c.String(200, "too late")
+	}))
+	app.Post("/form", func(c seatbelt.Context) error {
+		<-c.Done()
+		return c.Err()
+	})
+	app.SSE("/events", func(c seatbelt.Context, stream *seatbelt.EventStream) error {
+		time.Sleep(100 * time.Millisecond)
+		return stream.Send("", "", "not too late")
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		accept string
+		code   int
+		body   string
+		header string
+	}{
+		{path: "/slow", code: 503, body: "the request timed out"},
+		{path: "/fast", code: 201, body: "fast", header: "true"},
+		{path: "/route", code: 503, body: "the request timed out"},
+		{method: http.MethodPost, path: "/form", code: 503, body: "the request timed out"},
+		{path: "/events", accept: "text/event-stream", code: 200, body: "data: not too late\n\n"},
+
+		// The client can't opt out of the timeout.
+		{path: "/slow", accept: "text/event-stream", code: 503, body: "the request timed out"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, err := http.NewRequest(method, srv.URL+tc.path, nil)
+			if err != nil {
+				t.Fatalf("%+v creating http request", err)
+			}
+			req.Header.Set("Accept", tc.accept)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%+v executing http request", err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("%+v reading body", err)
+			}
+
+			if resp.StatusCode != tc.code {
+				t.Fatalf("expected %d but got %d", tc.code, resp.StatusCode)
+			}
+			if string(body) != tc.body {
+				t.Fatalf("expected body %s but got %s", tc.body, body)
+			}
+			if header := resp.Header.Get("X-Fast"); header != tc.header {
+				t.Fatalf("expected X-Fast header %q but got %q", tc.header, header)
+			}
+		})
+	}
+
+	select {
+	case err := <-writeErr:
+		if err != http.ErrHandlerTimeout {
+			t.Fatalf("expected write after timeout to fail with ErrHandlerTimeout but got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for slow handler")
+	}
+}
+
+func TestTimeoutContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("earlier parent deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		parent, _ := ctx.Deadline()
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		c := seatbelt.NewTestContext(httptest.NewRecorder(), r)
+
+		if err := seatbelt.Timeout(time.Hour)(func(c seatbelt.Context) error {
+			if deadline, ok := c.Deadline(); !ok || !deadline.Equal(parent) {
+				t.Errorf("expected the parent's deadline %s but got %s", parent, deadline)
+			}
+			return nil
+		})(c); err != nil {
+			t.Fatalf("%+v running handler", err)
+		}
+	})
+
+	t.Run("values are shared", func(t *testing.T) {
+		c := seatbelt.NewTestContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if err := seatbelt.Timeout(time.Second)(func(c seatbelt.Context) error {
+			c.Set("current_user", "ben")
+			return nil
+		})(c); err != nil {
+			t.Fatalf("%+v running handler", err)
+		}
+		if user := c.Get("current_user"); user != "ben" {
+			t.Fatalf("expected ben but got %v", user)
+		}
+	})
+
+	t.Run("panics keep their stack", func(t *testing.T) {
+		c := seatbelt.NewTestContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		defer func() {
+			perr, ok := recover().(*seatbelt.PanicError)
+			if !ok {
+				t.Fatalf("expected a *PanicError but got %#v", perr)
+			}
+			if perr.Value != "boom" || !strings.Contains(string(perr.Stack), "timeout_test.go") {
+				t.Fatalf("expected the handler's stack in %s", perr)
+			}
+		}()
+
+		seatbelt.Timeout(time.Second)(func(c seatbelt.Context) error {
+			panic("boom")
+		})(c)
+	})
+}
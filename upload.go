@@ -0,0 +1,330 @@
+package seatbelt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultMaxUploadSize is the maximum size of a form's body, unless it's
+// changed with the MaxUploadSize option.
+const defaultMaxUploadSize = 32 << 20
+
+// ErrUploadTooLarge is wrapped by the HTTPError returned when the body of a
+// request is larger than the MaxUploadSize option, or an uploaded file is
+// larger than the MaxSize of CheckUpload, ie,
+//
+//	if errors.Is(err, seatbelt.ErrUploadTooLarge) {
+//		// ...
+//	}
+var ErrUploadTooLarge = errors.New("the upload is too large")
+
+// FormFile returns the first file uploaded with the given field name. If
+// no file was uploaded, http.ErrMissingFile is returned.
+func (c *context) FormFile(name string) (*multipart.FileHeader, error) {
+	if err := c.parseForm(); err != nil {
+		return nil, err
+	}
+	if c.r.MultipartForm == nil || len(c.r.MultipartForm.File[name]) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return c.r.MultipartForm.File[name][0], nil
+}
+
+// multipartForms holds the multipart forms parsed while serving a request.
+// net/http only removes the temporary files of a form parsed on the request
+// it passed to the handler, but Set and the Timeout middleware replace the
+// context's request with a copy, so every copy of a context shares its
+// multipartForms, and the files are removed once the request is served.
+type multipartForms struct {
+	mu      sync.Mutex
+	forms   []*multipart.Form
+	removed bool
+}
+
+// add records the given form. If the request has already been served, ie,
+// by a handler that's still running after it timed out, the form's files
+// are removed straight away, since nothing can be done with them.
+func (m *multipartForms) add(form *multipart.Form) {
+	if m == nil || form == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.removed {
+		form.RemoveAll()
+		return
+	}
+	m.forms = append(m.forms, form)
+}
+
+// removeAll removes the temporary files of every recorded form.
+func (m *multipartForms) removeAll() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, form := range m.forms {
+		form.RemoveAll()
+	}
+	m.forms = nil
+	m.removed = true
+}
+
+// maxMemory returns the maximum number of bytes of a multipart form that
+// are held in memory.
+func (c *context) maxMemory() int64 {
+	if c.app != nil && c.app.maxMemory > 0 {
+		return c.app.maxMemory
+	}
+	return defaultMaxMemory
+}
+
+// maxUploadSize returns the maximum size of a request body, or zero if
+// there is no limit.
+func (c *context) maxUploadSize() int64 {
+	if c.app == nil || c.app.maxUploadSize == 0 {
+		return defaultMaxUploadSize
+	}
+	if c.app.maxUploadSize < 0 {
+		return 0
+	}
+	return c.app.maxUploadSize
+}
+
+// A limitedBody reads from a request body until the limit is exceeded,
+// after which it returns its error. Since the multipart and form parsers
+// don't preserve the errors they encounter, exceeded records whether the
+// limit was reached.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+	err       error
+}
+
+// Read implements the io.Reader interface.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		b.exceeded = true
+		return 0, b.err
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.exceeded = true
+		return n, b.err
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// An UploadOption describes the files that CheckUpload accepts.
+type UploadOption struct {
+	// MaxSize is the maximum size of the file in bytes. Zero means there is
+	// no limit.
+	MaxSize int64
+
+	// Types is a list of the content types that are accepted, ie,
+	// "image/png", or "image/*". The content type is detected from the
+	// contents of the file, rather than trusting the one sent by the
+	// browser. If empty, every type is accepted.
+	Types []string
+
+	// Extensions is a list of the file extensions that are accepted, ie,
+	// ".png". If empty, every extension is accepted.
+	Extensions []string
+}
+
+// CheckUpload returns an HTTPError if the given file is too large, or isn't
+// one of the accepted types or extensions, ie,
+//
+//	avatar, err := c.FormFile("avatar")
+//	if err != nil {
+//		return err
+//	}
+//	if err := seatbelt.CheckUpload(avatar, seatbelt.UploadOption{
+//		MaxSize: 2 << 20,
+//		Types:   []string{"image/png", "image/jpeg"},
+//	}); err != nil {
+//		return err
+//	}
+func CheckUpload(fh *multipart.FileHeader, opt UploadOption) error {
+	if opt.MaxSize > 0 && fh.Size > opt.MaxSize {
+		return tooLargeError(ErrUploadTooLarge)
+	}
+
+	if len(opt.Extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(fh.Filename))
+		allowed := false
+		for _, e := range opt.Extensions {
+			if strings.EqualFold(ext, e) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return NewHTTPError(http.StatusUnsupportedMediaType, "files with the extension "+ext+" are not allowed")
+		}
+	}
+
+	if len(opt.Types) > 0 {
+		contentType, err := DetectContentType(fh)
+		if err != nil {
+			return err
+		}
+		allowed := false
+		for _, t := range opt.Types {
+			if matched, _ := path.Match(t, contentType); matched || t == contentType {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return NewHTTPError(http.StatusUnsupportedMediaType, "files of type "+contentType+" are not allowed")
+		}
+	}
+
+	return nil
+}
+
+// DetectContentType returns the content type of the given file, detected
+// from its first 512 bytes, as http.DetectContentType does. Any parameters,
+// ie, the charset, are removed.
+func DetectContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType, nil
+}
+
+// A Storage saves uploaded files.
+type Storage interface {
+	// Save saves the given file under a generated name, and returns that
+	// name.
+	Save(fh *multipart.FileHeader) (string, error)
+
+	// Open opens the file with the given name.
+	Open(name string) (io.ReadCloser, error)
+
+	// Delete deletes the file with the given name.
+	Delete(name string) error
+}
+
+// ErrInvalidFileName is returned when attempting to open or delete a file
+// with a name that wasn't generated by a Storage, ie, one that contains a
+// path separator.
+var ErrInvalidFileName = errors.New("seatbelt: invalid file name")
+
+// DiskStorage is a Storage that saves uploaded files in a directory on the
+// local disk.
+type DiskStorage struct {
+	dir string
+}
+
+// NewDiskStorage returns a new DiskStorage that saves files in the given
+// directory, which is created if it doesn't exist.
+func NewDiskStorage(dir string) *DiskStorage {
+	return &DiskStorage{dir: dir}
+}
+
+// Save saves the given file under a randomly generated name, which keeps
+// the file's original extension, so that the name of an uploaded file can
+// never overwrite another file or escape the directory.
+func (s *DiskStorage) Save(fh *multipart.FileHeader) (string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(b) + safeExt(fh.Filename)
+
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(filepath.Join(s.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return name, dst.Close()
+}
+
+// Open opens the file with the given name.
+func (s *DiskStorage) Open(name string) (io.ReadCloser, error) {
+	if !isStorageName(name) {
+		return nil, ErrInvalidFileName
+	}
+	return os.Open(filepath.Join(s.dir, name))
+}
+
+// Delete deletes the file with the given name.
+func (s *DiskStorage) Delete(name string) error {
+	if !isStorageName(name) {
+		return ErrInvalidFileName
+	}
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// isStorageName returns true if the given name refers to a file directly
+// within the storage directory.
+func isStorageName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// safeExt returns the lowercased extension of the given file name, or an
+// empty string if it contains anything other than letters and digits.
+func safeExt(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) < 2 || len(ext) > 16 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
@@ -0,0 +1,343 @@
+package seatbelt_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+// pngHeader is enough of a PNG file for its content type to be detected.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// newUploadRequest returns a multipart request with the given fields and
+// files.
+func newUploadRequest(t *testing.T, fields map[string]string, files map[string][]byte) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	for name, content := range files {
+		field, filename := name, name
+		if i := strings.IndexByte(name, ':'); i >= 0 {
+			field, filename = name[:i], name[i+1:]
+		}
+		w, err := mw.CreateFormFile(field, filename)
+		if err != nil {
+			t.Fatalf("%+v creating form file", err)
+		}
+		w.Write(content)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("%+v closing multipart writer", err)
+	}
+
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestContextFormFile(t *testing.T) {
+	r := newUploadRequest(t, map[string]string{"title": "Avatar"}, map[string][]byte{
+		"avatar:me.png":        pngHeader,
+		"attachments[]:a.txt":  []byte("a"),
+		"gallery[0][image]:g0": pngHeader,
+	})
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r, nil)
+
+	fh, err := c.FormFile("avatar")
+	if err != nil {
+		t.Fatalf("%+v getting form file", err)
+	}
+	if fh.Filename != "me.png" {
+		t.Fatalf("expected me.png but got %s", fh.Filename)
+	}
+	if _, err := c.FormFile("missing"); err != http.ErrMissingFile {
+		t.Fatalf("expected http.ErrMissingFile but got %v", err)
+	}
+
+	var v struct {
+		Title       string
+		Avatar      *multipart.FileHeader
+		Attachments []*multipart.FileHeader
+		Gallery     []struct {
+			Image *multipart.FileHeader
+		}
+	}
+	if err := c.Params(&v); err != nil {
+		t.Fatalf("%+v decoding params", err)
+	}
+
+	if v.Title != "Avatar" {
+		t.Fatalf("expected title Avatar but got %s", v.Title)
+	}
+	if v.Avatar == nil || v.Avatar.Filename != "me.png" {
+		t.Fatalf("expected avatar to be bound but got %+v", v.Avatar)
+	}
+	if len(v.Attachments) != 1 || v.Attachments[0].Filename != "a.txt" {
+		t.Fatalf("expected one attachment but got %+v", v.Attachments)
+	}
+	if len(v.Gallery) != 1 || v.Gallery[0].Image == nil || v.Gallery[0].Image.Filename != "g0" {
+		t.Fatalf("expected gallery image to be bound but got %+v", v.Gallery)
+	}
+
+	f, err := v.Avatar.Open()
+	if err != nil {
+		t.Fatalf("%+v opening bound file", err)
+	}
+	defer f.Close()
+	if content, _ := ioutil.ReadAll(f); !bytes.Equal(content, pngHeader) {
+		t.Fatalf("expected bound file content to be readable but got %q", content)
+	}
+}
+
+func TestMaxUploadSize(t *testing.T) {
+	app := seatbelt.New(seatbelt.Option{MaxUploadSize: 1024})
+	app.Post("/", func(c seatbelt.Context) error {
+		if _, err := c.FormFile("file"); err != nil {
+			return err
+		}
+		return c.NoContent()
+	})
+
+	// Respond with the error's status code, rather than redirecting back to
+	// the form.
+	app.SetErrorHandler(func(c seatbelt.Context, err error) {
+		code := http.StatusInternalServerError
+		if herr, ok := err.(*seatbelt.HTTPError); ok {
+			code = herr.Code
+		}
+		c.String(code, err.Error())
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	for _, size := range []int{10, 4096} {
+		r := newUploadRequest(t, nil, map[string][]byte{"file": bytes.Repeat([]byte("a"), size)})
+
+		// Send the body without a length, so that the limit is enforced
+		// while it's read rather than up front.
+		req, err := http.NewRequest("POST", srv.URL+"/", ioutil.NopCloser(r.Body))
+		if err != nil {
+			t.Fatalf("%+v creating request", err)
+		}
+		req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%+v executing http request", err)
+		}
+		resp.Body.Close()
+
+		expected := http.StatusNoContent
+		if size > 1024 {
+			expected = http.StatusRequestEntityTooLarge
+		}
+		if resp.StatusCode != expected {
+			t.Fatalf("expected HTTP %d for a %d byte upload but got %d", expected, size, resp.StatusCode)
+		}
+	}
+}
+
+func TestMaxUploadSizeDefault(t *testing.T) {
+	cases := []struct {
+		name     string
+		max      int64
+		expected int
+	}{
+		{name: "default", max: 0, expected: http.StatusRequestEntityTooLarge},
+		{name: "disabled", max: -1, expected: http.StatusNoContent},
+	}
+
+	content := bytes.Repeat([]byte("a"), 33<<20)
+
+	for _, tc := range cases {
+		var uploadErr error
+
+		app := seatbelt.New(seatbelt.Option{MaxUploadSize: tc.max})
+		app.Post("/", func(c seatbelt.Context) error {
+			if _, err := c.FormFile("file"); err != nil {
+				uploadErr = err
+				return err
+			}
+			return c.NoContent()
+		})
+		app.SetErrorHandler(func(c seatbelt.Context, err error) {
+			var herr *seatbelt.HTTPError
+			if errors.As(err, &herr) {
+				c.String(herr.Code, err.Error())
+				return
+			}
+			c.String(http.StatusInternalServerError, err.Error())
+		})
+
+		srv := httptest.NewServer(app)
+
+		r := newUploadRequest(t, nil, map[string][]byte{"file": content})
+		req, err := http.NewRequest("POST", srv.URL+"/", ioutil.NopCloser(r.Body))
+		if err != nil {
+			t.Fatalf("%s: %+v creating request", tc.name, err)
+		}
+		req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %+v executing http request", tc.name, err)
+		}
+		resp.Body.Close()
+		srv.Close()
+
+		if resp.StatusCode != tc.expected {
+			t.Fatalf("%s: expected HTTP %d but got %d", tc.name, tc.expected, resp.StatusCode)
+		}
+		if tc.expected == http.StatusRequestEntityTooLarge && !errors.Is(uploadErr, seatbelt.ErrUploadTooLarge) {
+			t.Fatalf("%s: expected ErrUploadTooLarge but got %v", tc.name, uploadErr)
+		}
+	}
+}
+
+func TestCheckUpload(t *testing.T) {
+	r := newUploadRequest(t, nil, map[string][]byte{
+		"image:photo.png":   pngHeader,
+		"fake:photo.png":    []byte("<html><body>not an image</body></html>"),
+		"script:script.exe": []byte("MZ"),
+	})
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r, nil)
+
+	opt := seatbelt.UploadOption{
+		MaxSize:    1024,
+		Types:      []string{"image/*"},
+		Extensions: []string{".png", ".jpg"},
+	}
+
+	cases := []struct {
+		field string
+		code  int
+	}{
+		{field: "image", code: 0},
+		{field: "fake", code: http.StatusUnsupportedMediaType},
+		{field: "script", code: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range cases {
+		fh, err := c.FormFile(tc.field)
+		if err != nil {
+			t.Fatalf("%+v getting form file %s", err, tc.field)
+		}
+
+		err = seatbelt.CheckUpload(fh, opt)
+		if tc.code == 0 {
+			if err != nil {
+				t.Fatalf("%s: %+v checking upload", tc.field, err)
+			}
+			continue
+		}
+		herr, ok := err.(*seatbelt.HTTPError)
+		if !ok || herr.Code != tc.code {
+			t.Fatalf("%s: expected HTTP %d error but got %v", tc.field, tc.code, err)
+		}
+	}
+
+	fh, _ := c.FormFile("image")
+	if err := seatbelt.CheckUpload(fh, seatbelt.UploadOption{MaxSize: 4}); !errors.Is(err, seatbelt.ErrUploadTooLarge) {
+		t.Fatalf("expected ErrUploadTooLarge but got %v", err)
+	}
+}
+
+func TestDiskStorage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "uploads")
+	storage := seatbelt.NewDiskStorage(dir)
+
+	r := newUploadRequest(t, nil, map[string][]byte{"file:../../Evil Name.PNG": pngHeader})
+	c := seatbelt.NewTestContext(httptest.NewRecorder(), r, nil)
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		t.Fatalf("%+v getting form file", err)
+	}
+
+	name, err := storage.Save(fh)
+	if err != nil {
+		t.Fatalf("%+v saving file", err)
+	}
+	if !strings.HasSuffix(name, ".png") || strings.ContainsAny(name, `/\ `) {
+		t.Fatalf("expected a generated name with the original extension but got %s", name)
+	}
+
+	f, err := storage.Open(name)
+	if err != nil {
+		t.Fatalf("%+v opening saved file", err)
+	}
+	content, _ := ioutil.ReadAll(f)
+	f.Close()
+	if !bytes.Equal(content, pngHeader) {
+		t.Fatalf("expected saved content but got %q", content)
+	}
+
+	if _, err := storage.Open("../" + name); err != seatbelt.ErrInvalidFileName {
+		t.Fatalf("expected ErrInvalidFileName but got %v", err)
+	}
+
+	if err := storage.Delete(name); err != nil {
+		t.Fatalf("%+v deleting file", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be deleted but got %v", err)
+	}
+}
+
+func TestMultipartTempFilesRemoved(t *testing.T) {
+	// Multipart forms spill to temporary files in the system's temporary
+	// directory, so point it at an empty directory that can be checked.
+	dir := t.TempDir()
+	tmpdir := os.Getenv("TMPDIR")
+	os.Setenv("TMPDIR", dir)
+	defer os.Setenv("TMPDIR", tmpdir)
+
+	app := seatbelt.New(seatbelt.Option{MaxMemory: 1, Timeout: 5 * time.Second})
+	app.Post("/", func(c seatbelt.Context) error {
+		// Set replaces the context's request with a copy, as the Timeout
+		// middleware does.
+		c.Set("user", "jane")
+
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return c.NoContent()
+	})
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, newUploadRequest(t, nil, map[string][]byte{"file": bytes.Repeat([]byte("a"), 4096)}))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected HTTP 204 but got %d", w.Code)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("%+v reading temporary directory", err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected the temporary files to be removed but found %d", len(files))
+	}
+}
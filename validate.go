@@ -0,0 +1,459 @@
+package seatbelt
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidationErrors maps the name of each invalid field to the problems
+// found with it. The name of a field is its params tag if it has one, or its
+// lowercased name otherwise, and the fields of nested structs are named with
+// brackets, ie, address[city], so that they match the names of the inputs
+// they were submitted with.
+type ValidationErrors map[string][]string
+
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	msgs := make([]string, 0, len(e))
+	for _, field := range fields {
+		for _, msg := range e[field] {
+			msgs = append(msgs, field+" "+msg)
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Add adds a problem with the given field.
+func (e ValidationErrors) Add(field, message string) {
+	e[field] = append(e[field], message)
+}
+
+// Get returns the first problem with the given field, or an empty string if
+// it's valid.
+func (e ValidationErrors) Get(field string) string {
+	if msgs := e[field]; len(msgs) > 0 {
+		return msgs[0]
+	}
+	return ""
+}
+
+// Has returns true if there's a problem with the given field.
+func (e ValidationErrors) Has(field string) bool {
+	return len(e[field]) > 0
+}
+
+// A ValidatorFunc is a custom validation rule, which is called with the
+// value of the field, and the parameter of the rule, ie, "3" for
+// `validate:"sku=3"`. It returns an error describing the problem, ie,
+// errors.New("must be a valid SKU"), if the value is invalid.
+type ValidatorFunc func(value interface{}, param string) error
+
+// Validate validates the given struct using the rules in the validate tag of
+// each of its fields. If any field is invalid, the returned error is
+// ValidationErrors.
+//
+// The rules are separated by commas, ie,
+//
+//	type Product struct {
+//		Name  string `validate:"required,max=100"`
+//		Price int    `validate:"required,min=1"`
+//		Kind  string `validate:"oneof=book film"`
+//		Code  string `validate:"regexp=^[A-Z]{3}-[0-9]+$"`
+//	}
+//
+// The built in rules are required, min, max, len, email, url, oneof, and
+// regexp. Since a regular expression may contain commas, regexp must be the
+// last rule. For strings, min, max, and len count characters, for slices
+// and maps they count items, and for numbers they compare the value.
+//
+// Every rule other than required is skipped when the field is empty, so
+// that optional fields are only validated when they're given. A number is
+// empty when it's zero, so Price above needs the required rule to reject 0.
+//
+// Custom rules registered with App.RegisterValidator aren't known to
+// Validate, use App.Validate instead.
+func Validate(v interface{}) error {
+	return validate(v, nil)
+}
+
+// Validate validates the given struct like the package level Validate, but
+// also knows about the custom rules registered with RegisterValidator.
+func (a *App) Validate(v interface{}) error {
+	return validate(v, a.validators)
+}
+
+// RegisterValidator registers a custom validation rule with the given name,
+// that can be used in the validate tag of any struct bound with
+// Context.Bind. Unlike the built in rules, custom rules are called even
+// when the field is empty.
+//
+// RegisterValidator is not safe to call concurrently with requests, so
+// validators should be registered before the application starts.
+func (a *App) RegisterValidator(name string, fn ValidatorFunc) {
+	if a.validators == nil {
+		a.validators = make(map[string]ValidatorFunc)
+	}
+	a.validators[name] = fn
+}
+
+// validate validates the given struct with the built in rules, and the
+// given custom rules.
+func validate(v interface{}, custom map[string]ValidatorFunc) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	// The structs being validated are tracked by their address, so that a
+	// struct that points back to itself, ie, through a parent field, isn't
+	// validated forever.
+	seen := make(map[visit]bool)
+	if ptr := reflect.ValueOf(v); ptr.Kind() == reflect.Ptr {
+		seen[visit{ptr.Pointer(), rv.Type()}] = true
+	}
+
+	errs := make(ValidationErrors)
+	if err := validateStruct(rv, "", custom, errs, seen); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// A visit is a struct reached through a pointer while validating.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// validateStruct validates each field of the given struct, adding any
+// problems to errs. The prefix is the name of the struct's own field, if
+// it's nested.
+func validateStruct(rv reflect.Value, prefix string, custom map[string]ValidatorFunc, errs ValidationErrors, seen map[visit]bool) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		name := paramName(sf)
+		if name == "-" {
+			continue
+		}
+
+		field := rv.Field(i)
+
+		// The fields of embedded structs are treated as the parent's own.
+		if sf.Anonymous && indirectType(sf.Type).Kind() == reflect.Struct {
+			if f, ok := indirect(field); ok {
+				if err := validateStruct(f, prefix, custom, errs, seen); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "[" + name + "]"
+		}
+
+		if tag, ok := sf.Tag.Lookup("validate"); ok && tag != "" && tag != "-" {
+			if err := validateField(field, key, tag, custom, errs); err != nil {
+				return err
+			}
+		}
+
+		if err := validateNested(field, key, custom, errs, seen); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateNested validates the given field if it's a struct, or the
+// elements of the given field if it's a slice of structs. Structs that are
+// already being validated further up, ie, in a cycle of pointers, are
+// skipped.
+func validateNested(field reflect.Value, key string, custom map[string]ValidatorFunc, errs ValidationErrors, seen map[visit]bool) error {
+	var ptrs []visit
+	for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return nil
+		}
+		if field.Kind() == reflect.Ptr {
+			v := visit{field.Pointer(), field.Type().Elem()}
+			if seen[v] {
+				return nil
+			}
+			ptrs = append(ptrs, v)
+		}
+		field = field.Elem()
+	}
+
+	// Only the structs on the current path are tracked, so that a struct
+	// that's pointed to by two fields is validated under both of their
+	// names.
+	for _, v := range ptrs {
+		seen[v] = true
+	}
+	defer func() {
+		for _, v := range ptrs {
+			delete(seen, v)
+		}
+	}()
+
+	switch field.Kind() {
+	case reflect.Struct:
+		return validateStruct(field, key, custom, errs, seen)
+
+	case reflect.Slice, reflect.Array:
+		if indirectType(field.Type().Elem()).Kind() != reflect.Struct {
+			return nil
+		}
+		for i := 0; i < field.Len(); i++ {
+			if err := validateNested(field.Index(i), key+"["+strconv.Itoa(i)+"]", custom, errs, seen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateField applies the rules in the given validate tag to the given
+// field.
+func validateField(field reflect.Value, key, tag string, custom map[string]ValidatorFunc, errs ValidationErrors) error {
+	value, present := indirect(field)
+	empty := !present || isEmptyValue(value)
+
+	for _, rule := range splitRules(tag) {
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		if name == "required" {
+			if empty {
+				errs.Add(key, "is required")
+				return nil
+			}
+			continue
+		}
+
+		if fn, ok := custom[name]; ok {
+			var v interface{}
+			if present {
+				v = value.Interface()
+			}
+			if err := fn(v, param); err != nil {
+				errs.Add(key, err.Error())
+			}
+			continue
+		}
+
+		if empty {
+			continue
+		}
+
+		msg, err := applyRule(value, name, param)
+		if err != nil {
+			return err
+		}
+		if msg != "" {
+			errs.Add(key, msg)
+		}
+	}
+
+	return nil
+}
+
+// applyRule applies the built in rule with the given name and parameter to
+// the given value, and returns a description of the problem if it's
+// invalid.
+func applyRule(value reflect.Value, name, param string) (string, error) {
+	switch name {
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return "", fmt.Errorf("seatbelt: invalid parameter %q for validation rule %s", param, name)
+		}
+		return compareRule(value, name, param, n), nil
+
+	case "email":
+		s := fmt.Sprint(value.Interface())
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "must be a valid email address", nil
+		}
+
+	case "url":
+		u, err := url.ParseRequestURI(fmt.Sprint(value.Interface()))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be a valid URL", nil
+		}
+
+	case "oneof":
+		options := strings.Fields(param)
+		s := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if s == option {
+				return "", nil
+			}
+		}
+		return "must be one of " + strings.Join(options, ", "), nil
+
+	case "regexp":
+		re, err := compileRule(param)
+		if err != nil {
+			return "", fmt.Errorf("seatbelt: invalid regexp for validation rule: %w", err)
+		}
+		if !re.MatchString(fmt.Sprint(value.Interface())) {
+			return "is invalid", nil
+		}
+
+	default:
+		return "", fmt.Errorf("seatbelt: unknown validation rule %q", name)
+	}
+
+	return "", nil
+}
+
+// compareRule applies the min, max, or len rules, which compare the length
+// of strings, slices, and maps, or the value of numbers.
+func compareRule(value reflect.Value, name, param string, n float64) string {
+	var (
+		actual float64
+		verb   = "must be"
+		unit   string
+	)
+
+	switch value.Kind() {
+	case reflect.String:
+		actual, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual, verb, unit = float64(value.Len()), "must have", " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	default:
+		return ""
+	}
+
+	switch {
+	case name == "min" && actual < n:
+		return verb + " at least " + param + unit
+	case name == "max" && actual > n:
+		return verb + " at most " + param + unit
+	case name == "len" && actual != n:
+		return verb + " exactly " + param + unit
+	}
+	return ""
+}
+
+// regexps caches the compiled regular expressions of regexp rules.
+var regexps sync.Map
+
+// compileRule returns the compiled regular expression for a regexp rule.
+func compileRule(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Store(pattern, re)
+	return re, nil
+}
+
+// splitRules splits a validate tag into its rules. Everything following a
+// regexp rule is part of its pattern.
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regexp=") {
+			return append(rules, tag)
+		}
+
+		rule := tag
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			rule, tag = tag[:i], tag[i+1:]
+		} else {
+			tag = ""
+		}
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// paramName returns the name a struct field is bound from, which is its
+// params tag if it has one, or its lowercased name otherwise.
+func paramName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("params"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" {
+			return name
+		}
+	}
+	return strings.ToLower(sf.Name)
+}
+
+// indirect dereferences the given value until it isn't a pointer or
+// interface, and returns false if it's nil.
+func indirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+// indirectType dereferences the given type until it isn't a pointer.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isEmptyValue returns true if the given value is its type's zero value, or
+// an empty slice or map.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
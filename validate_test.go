@@ -0,0 +1,241 @@
+package seatbelt_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+type address struct {
+	City string `validate:"required"`
+}
+
+type signup struct {
+	Name     string    `validate:"required,min=2,max=10"`
+	Email    string    `params:"email_address" validate:"required,email"`
+	Website  string    `validate:"url"`
+	Age      int       `validate:"min=18,max=130"`
+	Plan     string    `validate:"oneof=free pro"`
+	Code     string    `validate:"len=4,regexp=^[A-Z]{2},[0-9]$"`
+	Tags     []string  `validate:"max=2"`
+	Address  address   `params:"address"`
+	Contacts []address `params:"contacts"`
+	Nickname *string   `validate:"min=3"`
+}
+
+func TestValidate(t *testing.T) {
+	nickname := "al"
+
+	cases := []struct {
+		name     string
+		value    signup
+		expected seatbelt.ValidationErrors
+	}{
+		{
+			name: "valid",
+			value: signup{
+				Name:    "Ben",
+				Email:   "ben@example.com",
+				Website: "https://example.com",
+				Age:     30,
+				Plan:    "pro",
+				Code:    "AB,1",
+				Address: address{City: "Toronto"},
+			},
+			expected: nil,
+		},
+		{
+			name: "invalid",
+			value: signup{
+				Name:     "B",
+				Email:    "not an email",
+				Website:  "example.com",
+				Age:      12,
+				Plan:     "enterprise",
+				Code:     "ABC1",
+				Tags:     []string{"a", "b", "c"},
+				Contacts: []address{{City: "Ottawa"}, {}},
+				Nickname: &nickname,
+			},
+			expected: seatbelt.ValidationErrors{
+				"name":              {"must be at least 2 characters"},
+				"email_address":     {"must be a valid email address"},
+				"website":           {"must be a valid URL"},
+				"age":               {"must be at least 18"},
+				"plan":              {"must be one of free, pro"},
+				"code":              {"is invalid"},
+				"tags":              {"must have at most 2 items"},
+				"address[city]":     {"is required"},
+				"contacts[1][city]": {"is required"},
+				"nickname":          {"must be at least 3 characters"},
+			},
+		},
+		{
+			name:  "required fields skip other rules when empty",
+			value: signup{Address: address{City: "Toronto"}},
+			expected: seatbelt.ValidationErrors{
+				"name":          {"is required"},
+				"email_address": {"is required"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		err := seatbelt.Validate(&tc.value)
+		if tc.expected == nil {
+			if err != nil {
+				t.Fatalf("%s: %+v validating", tc.name, err)
+			}
+			continue
+		}
+
+		var verrs seatbelt.ValidationErrors
+		if !errors.As(err, &verrs) {
+			t.Fatalf("%s: expected ValidationErrors but got %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(verrs, tc.expected) {
+			t.Fatalf("%s: expected %v but got %v", tc.name, tc.expected, verrs)
+		}
+	}
+}
+
+func TestValidateUnknownRule(t *testing.T) {
+	v := struct {
+		Name string `validate:"sku"`
+	}{Name: "x"}
+
+	err := seatbelt.Validate(&v)
+	if err == nil || !strings.Contains(err.Error(), `unknown validation rule "sku"`) {
+		t.Fatalf("expected unknown rule error but got %v", err)
+	}
+}
+
+func TestValidateZeroNumbers(t *testing.T) {
+	v := struct {
+		Price    int `validate:"required,min=1"`
+		Discount int `validate:"min=1"`
+		Stock    int `validate:"min=1"`
+	}{Stock: -1}
+
+	var verrs seatbelt.ValidationErrors
+	if err := seatbelt.Validate(&v); !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors but got %v", err)
+	}
+
+	expected := seatbelt.ValidationErrors{
+		"price": {"is required"},
+		"stock": {"must be at least 1"},
+	}
+	if !reflect.DeepEqual(verrs, expected) {
+		t.Fatalf("expected %v but got %v", expected, verrs)
+	}
+}
+
+func TestValidateCycle(t *testing.T) {
+	type category struct {
+		Name   string    `validate:"required"`
+		Parent *category `params:"parent"`
+		Next   *category `params:"next"`
+	}
+
+	shared := &category{}
+	root := &category{Parent: shared, Next: shared}
+	shared.Parent = root
+
+	var verrs seatbelt.ValidationErrors
+	if err := seatbelt.Validate(root); !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors but got %v", err)
+	}
+
+	expected := seatbelt.ValidationErrors{
+		"name":         {"is required"},
+		"parent[name]": {"is required"},
+		"next[name]":   {"is required"},
+	}
+	if !reflect.DeepEqual(verrs, expected) {
+		t.Fatalf("expected %v but got %v", expected, verrs)
+	}
+}
+
+func TestAppValidate(t *testing.T) {
+	app := seatbelt.New()
+	app.RegisterValidator("sku", func(value interface{}, param string) error {
+		if s, _ := value.(string); !strings.HasPrefix(s, "SKU-") {
+			return errors.New("must be a valid SKU")
+		}
+		return nil
+	})
+
+	v := struct {
+		Code string `validate:"sku"`
+	}{Code: "123"}
+
+	var verrs seatbelt.ValidationErrors
+	if err := app.Validate(&v); !errors.As(err, &verrs) || verrs.Get("code") != "must be a valid SKU" {
+		t.Fatalf("expected the custom rule to fail but got %v", err)
+	}
+
+	if err := seatbelt.Validate(&v); err == nil || !strings.Contains(err.Error(), `unknown validation rule "sku"`) {
+		t.Fatalf("expected unknown rule error from the package level Validate but got %v", err)
+	}
+}
+
+func TestContextBind(t *testing.T) {
+	type product struct {
+		Name string `validate:"required"`
+		SKU  string `validate:"required,sku"`
+	}
+
+	app := seatbelt.New()
+	app.RegisterValidator("sku", func(value interface{}, param string) error {
+		if s, _ := value.(string); !strings.HasPrefix(s, "SKU-") {
+			return errors.New("must start with SKU-")
+		}
+		return nil
+	})
+	app.Post("/products", func(c seatbelt.Context) error {
+		var p product
+		if err := c.Bind(&p); err != nil {
+			return err
+		}
+		return c.String(201, p.Name)
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.PostForm(srv.URL+"/products", url.Values{"name": {"Widget"}, "sku": {"SKU-1"}})
+	if err != nil {
+		t.Fatalf("%+v executing http request", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected HTTP 201 but got %d", resp.StatusCode)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/products", strings.NewReader("sku=1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := seatbelt.NewTestContext(w, r, nil)
+
+	var v struct {
+		Name string `validate:"required"`
+		SKU  string `validate:"required"`
+	}
+	var verrs seatbelt.ValidationErrors
+	if err := c.Bind(&v); !errors.As(err, &verrs) {
+		t.Fatalf("expected ValidationErrors but got %v", err)
+	}
+	if msg := verrs.Get("name"); msg != "is required" {
+		t.Fatalf("expected name to be required but got %q", msg)
+	}
+	if verrs.Has("sku") {
+		t.Fatalf("expected sku to be valid but got %v", verrs)
+	}
+}
@@ -0,0 +1,119 @@
+package seatbelt
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// watchInterval is how often a watcher polls the filesystem for changes.
+const watchInterval = 250 * time.Millisecond
+
+// A watcher polls a set of directories, and calls its onChange func whenever
+// a file within them is added, removed, or modified.
+//
+// Polling the modification times and sizes of files is far cheaper than
+// parsing them, and unlike inotify, works the same on every platform.
+type watcher struct {
+	interval time.Duration
+	onChange func()
+
+	mu   sync.Mutex
+	dirs []string
+	sum  uint64
+
+	done chan struct{}
+	once sync.Once
+}
+
+// newWatcher starts watching the given directories for changes.
+func newWatcher(onChange func(), dirs ...string) *watcher {
+	w := &watcher{
+		interval: watchInterval,
+		onChange: onChange,
+		dirs:     dirs,
+		done:     make(chan struct{}),
+	}
+	w.sum = w.fingerprint()
+
+	go w.run()
+
+	return w
+}
+
+// add starts watching another directory for changes.
+func (w *watcher) add(dir string) {
+	w.mu.Lock()
+	w.dirs = append(w.dirs, dir)
+	w.mu.Unlock()
+
+	sum := w.fingerprint()
+
+	w.mu.Lock()
+	w.sum = sum
+	w.mu.Unlock()
+}
+
+// close stops watching for changes.
+func (w *watcher) close() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+// run polls for changes until the watcher is closed.
+func (w *watcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case <-ticker.C:
+			sum := w.fingerprint()
+
+			w.mu.Lock()
+			changed := sum != w.sum
+			w.sum = sum
+			w.mu.Unlock()
+
+			if changed {
+				w.onChange()
+			}
+		}
+	}
+}
+
+// fingerprint returns a hash of the path, size, and modification time of
+// every file in the watched directories.
+func (w *watcher) fingerprint() uint64 {
+	w.mu.Lock()
+	dirs := make([]string, len(w.dirs))
+	copy(dirs, w.dirs)
+	w.mu.Unlock()
+
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+
+			h.Write([]byte(path))
+			binary.LittleEndian.PutUint64(buf, uint64(info.Size()))
+			h.Write(buf)
+			binary.LittleEndian.PutUint64(buf, uint64(info.ModTime().UnixNano()))
+			h.Write(buf)
+			return nil
+		})
+	}
+
+	return h.Sum64()
+}
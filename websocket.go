@@ -0,0 +1,486 @@
+package seatbelt
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocketGUID is appended to the client's key in order to compute the
+// Sec-WebSocket-Accept header, as described in RFC 6455 section 4.2.2.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// defaultReadLimit is the maximum size of a message read from a websocket,
+// unless it's changed with SetReadLimit.
+const defaultReadLimit = 1 << 20
+
+// closeTimeout is how long Close waits to write the close frame.
+const closeTimeout = time.Second
+
+// A MessageType is the type of a websocket message.
+type MessageType int
+
+// The types of websocket messages.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// The opcodes of websocket frames.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// The close codes defined in RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseAbnormalClosure  = 1006
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+// A CloseError is returned when reading from a websocket connection that
+// has been closed by the client.
+type CloseError struct {
+	Code   int    // The close code sent by the client.
+	Reason string // The reason sent by the client, if any.
+}
+
+// Error implements the error interface.
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return "seatbelt: websocket closed with code " + strconv.Itoa(e.Code)
+	}
+	return "seatbelt: websocket closed with code " + strconv.Itoa(e.Code) + ": " + e.Reason
+}
+
+// A Conn is a websocket connection.
+//
+// Only one goroutine may read from a Conn at a time, however, writes are
+// safe to call concurrently with each other and with reads.
+type Conn struct {
+	conn      net.Conn
+	br        *bufio.Reader
+	readLimit int64
+
+	mu          sync.Mutex
+	closeSent   bool
+	pongHandler func(data []byte)
+}
+
+// WebSocket routes websocket connections at the given path.
+//
+// Middleware runs before the connection is upgraded, so that it can reject
+// the request, ie, if the user isn't logged in. Since the connection is no
+// longer HTTP once the handler is called, the session can be read but not
+// changed.
+//
+// Browsers can't send the CSRF token with a websocket handshake, so instead
+// of checking it, requests are protected from cross-site forgery by their
+// Origin header. Requests from another origin, or without an Origin header,
+// are rejected with a 403 Forbidden, unless the origin is listed in the
+// WebSocketOrigins option. Clients other than browsers must set the Origin
+// header to connect.
+//
+// When the handler returns, the connection is closed. If the handler returns
+// an error, other than a CloseError from the connection being closed, the
+// connection is closed with CloseInternalError, and the error is passed to
+// the error handler. The response has already been committed by then, so
+// anything the error handler writes is discarded.
+//
+//	app.WebSocket("/echo", func(c seatbelt.Context, conn *seatbelt.Conn) error {
+//		for {
+//			typ, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return err
+//			}
+//			if err := conn.WriteMessage(typ, msg); err != nil {
+//				return err
+//			}
+//		}
+//	})
+func (a *App) WebSocket(path string, handle func(c Context, conn *Conn) error) {
+	a.handle("GET", path, func(c Context) error {
+		r := c.Request()
+		w := c.Response()
+
+		if !isUpgrade(r) || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			return NewHTTPError(http.StatusBadRequest, "expected a websocket upgrade request")
+		}
+		if r.Header.Get("Sec-WebSocket-Version") != "13" {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			return NewHTTPError(http.StatusUpgradeRequired)
+		}
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+			return NewHTTPError(http.StatusBadRequest, "invalid Sec-WebSocket-Key header")
+		}
+		if !a.checkOrigin(r) {
+			return NewHTTPError(http.StatusForbidden, "websocket origin not allowed")
+		}
+
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			return errors.New("seatbelt: the response writer does not support hijacking")
+		}
+		netConn, brw, err := hj.Hijack()
+		if err != nil {
+			return err
+		}
+
+		if _, err := brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"); err != nil {
+			netConn.Close()
+			return err
+		}
+		if err := brw.Flush(); err != nil {
+			netConn.Close()
+			return err
+		}
+
+		conn := &Conn{
+			conn:      netConn,
+			br:        brw.Reader,
+			readLimit: defaultReadLimit,
+		}
+
+		err = handle(c, conn)
+
+		var cerr *CloseError
+		switch {
+		case err == nil, errors.As(err, &cerr):
+			conn.Close(CloseNormalClosure, "")
+			return nil
+		default:
+			conn.Close(CloseInternalError, "")
+			return err
+		}
+	}, routeOption{stream: true})
+}
+
+// isUpgrade returns true if the request is asking to upgrade the
+// connection to another protocol.
+func isUpgrade(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// checkOrigin returns true if the request's origin is the same as the
+// request's host, or is one of the allowed origins. Requests without an
+// Origin header are rejected, since browsers always send one with a
+// websocket handshake, so one that's missing can't be trusted.
+func (a *App) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	for _, allowed := range a.websocketOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// websocketAccept returns the value of the Sec-WebSocket-Accept header for
+// the given key.
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SetReadLimit sets the maximum size, in bytes, of a message read from the
+// client. If a larger message is received, the connection is closed with
+// CloseMessageTooBig. The default is 1MB.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPongHandler sets a func that's called with the data of each pong
+// received from the client.
+func (c *Conn) SetPongHandler(fn func(data []byte)) {
+	c.mu.Lock()
+	c.pongHandler = fn
+	c.mu.Unlock()
+}
+
+// SetReadDeadline sets the deadline for reading from the connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writing to the connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// RemoteAddr returns the address of the client.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage reads the next text or binary message from the client.
+//
+// Pings are answered, and pongs are passed to the pong handler, while
+// waiting for a message. If the client closes the connection, a CloseError
+// is returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ     MessageType
+		message []byte
+	)
+
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+
+		case opPong:
+			c.mu.Lock()
+			fn := c.pongHandler
+			c.mu.Unlock()
+			if fn != nil {
+				fn(payload)
+			}
+			continue
+
+		case opClose:
+			// A close frame without a status code is answered with an empty
+			// one, otherwise the client's status code is echoed back, as
+			// described in RFC 6455 section 5.5.1.
+			if len(payload) == 0 {
+				c.closeWith(nil)
+				return 0, nil, &CloseError{Code: CloseNoStatusReceived}
+			}
+			if len(payload) < 2 {
+				return 0, nil, c.fail(CloseProtocolError, "invalid close frame")
+			}
+			code := int(binary.BigEndian.Uint16(payload))
+			if !validCloseCode(code) {
+				return 0, nil, c.fail(CloseProtocolError, "invalid close code "+strconv.Itoa(code))
+			}
+			if !utf8.Valid(payload[2:]) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8")
+			}
+			c.Close(code, "")
+			return 0, nil, &CloseError{Code: code, Reason: string(payload[2:])}
+
+		case opText, opBinary:
+			if typ != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected a continuation frame")
+			}
+			typ = MessageType(opcode)
+
+		case opContinuation:
+			if typ == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode "+strconv.Itoa(opcode))
+		}
+
+		if int64(len(message)+len(payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if typ == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8")
+			}
+			return typ, message, nil
+		}
+	}
+}
+
+// WriteMessage sends a text or binary message to the client.
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return fmt.Errorf("seatbelt: invalid websocket message type %d", typ)
+	}
+	return c.writeFrame(int(typ), data)
+}
+
+// Ping sends a ping to the client, which responds with a pong containing
+// the same data.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("seatbelt: websocket ping data must be 125 bytes or less")
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// Close sends a close frame with the given code and reason, unless one has
+// already been sent, and closes the connection.
+func (c *Conn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	return c.closeWith(payload)
+}
+
+// closeWith sends a close frame with the given payload, unless one has
+// already been sent, and closes the connection.
+func (c *Conn) closeWith(payload []byte) error {
+	c.mu.Lock()
+	sent := c.closeSent
+	c.mu.Unlock()
+
+	if !sent {
+		c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+		c.writeFrame(opClose, payload)
+	}
+
+	return c.conn.Close()
+}
+
+// validCloseCode returns true if the given close code can be sent in a
+// close frame. Codes like CloseNoStatusReceived and CloseAbnormalClosure
+// are reserved for reporting why a connection closed, and must never be
+// sent, as described in RFC 6455 section 7.4.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// fail closes the connection with the given code, and returns a CloseError
+// describing why.
+func (c *Conn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// readFrame reads a single frame from the client, and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	if !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	// Control frames can't be fragmented, and have a small payload, so that
+	// they can be interleaved with the frames of a message.
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length < 0 || length > c.readLimit {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// writeFrame writes a single, unfragmented, unmasked frame to the client.
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closeSent {
+		return errors.New("seatbelt: websocket connection is closed")
+	}
+	if opcode == opClose {
+		c.closeSent = true
+	}
+
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|byte(opcode))
+
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127)
+		frame = append(frame, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(frame[len(frame)-8:], uint64(n))
+	}
+	frame = append(frame, payload...)
+
+	_, err := c.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,340 @@
+package seatbelt_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bentranter/go-seatbelt"
+)
+
+// wsClient is a minimal websocket client, used to test the server's
+// handshake and framing directly.
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWebSocket performs the opening handshake with the given headers, and
+// returns the response status code, and the client if it succeeded.
+func dialWebSocket(t *testing.T, srv *httptest.Server, path string, header http.Header) (int, *wsClient) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("%+v dialing server", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, err := http.NewRequest("GET", srv.URL+path, nil)
+	if err != nil {
+		t.Fatalf("%+v creating request", err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatalf("%+v writing handshake", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("%+v reading handshake response", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return resp.StatusCode, nil
+	}
+
+	// The example key and accept value from RFC 6455 section 1.3.
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("expected valid Sec-WebSocket-Accept header but got %s", accept)
+	}
+
+	return resp.StatusCode, &wsClient{conn: conn, br: br}
+}
+
+// write sends a single masked frame.
+func (c *wsClient) write(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("%+v writing frame", err)
+	}
+}
+
+// read reads a single unmasked frame.
+func (c *wsClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.br, header); err != nil {
+		t.Fatalf("%+v reading frame", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("expected server frames to be unmasked")
+	}
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatalf("%+v reading payload", err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+func TestWebSocket(t *testing.T) {
+	finished := make(chan error, 1)
+
+	app := seatbelt.New()
+	app.Use(func(next func(seatbelt.Context) error) func(seatbelt.Context) error {
+		return func(c seatbelt.Context) error {
+			if c.Request().Header.Get("Authorization") != "secret" {
+				return seatbelt.NewHTTPError(http.StatusUnauthorized)
+			}
+			return next(c)
+		}
+	})
+	app.WebSocket("/echo", func(c seatbelt.Context, conn *seatbelt.Conn) error {
+		if c.Session() == nil {
+			t.Error("expected session to be available")
+		}
+
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				finished <- err
+				return err
+			}
+			if err := conn.WriteMessage(typ, msg); err != nil {
+				return err
+			}
+		}
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	t.Run("middleware runs before upgrade", func(t *testing.T) {
+		status, _ := dialWebSocket(t, srv, "/echo", nil)
+		if status != http.StatusUnauthorized {
+			t.Fatalf("expected HTTP 401 but got %d", status)
+		}
+	})
+
+	t.Run("cross origin requests are rejected", func(t *testing.T) {
+		status, _ := dialWebSocket(t, srv, "/echo", http.Header{
+			"Authorization": {"secret"},
+			"Origin":        {"https://evil.example.com"},
+		})
+		if status != http.StatusForbidden {
+			t.Fatalf("expected HTTP 403 but got %d", status)
+		}
+	})
+
+	t.Run("requests without an origin are rejected", func(t *testing.T) {
+		status, _ := dialWebSocket(t, srv, "/echo", http.Header{
+			"Authorization": {"secret"},
+		})
+		if status != http.StatusForbidden {
+			t.Fatalf("expected HTTP 403 but got %d", status)
+		}
+	})
+
+	t.Run("messages, pings, and close", func(t *testing.T) {
+		status, client := dialWebSocket(t, srv, "/echo", http.Header{
+			"Authorization": {"secret"},
+			"Origin":        {srv.URL},
+		})
+		if status != http.StatusSwitchingProtocols {
+			t.Fatalf("expected HTTP 101 but got %d", status)
+		}
+		defer client.conn.Close()
+
+		client.write(t, 0x1, []byte("hello"))
+		if opcode, payload := client.read(t); opcode != 0x1 || string(payload) != "hello" {
+			t.Fatalf("expected text echo but got opcode %d with %q", opcode, payload)
+		}
+
+		client.write(t, 0x9, []byte("ping"))
+		if opcode, payload := client.read(t); opcode != 0xa || string(payload) != "ping" {
+			t.Fatalf("expected pong but got opcode %d with %q", opcode, payload)
+		}
+
+		closePayload := make([]byte, 2)
+		binary.BigEndian.PutUint16(closePayload, seatbelt.CloseGoingAway)
+		client.write(t, 0x8, append(closePayload, "bye"...))
+
+		opcode, payload := client.read(t)
+		if opcode != 0x8 || binary.BigEndian.Uint16(payload) != seatbelt.CloseGoingAway {
+			t.Fatalf("expected close frame echoing code 1001 but got opcode %d with %v", opcode, payload)
+		}
+
+		var cerr *seatbelt.CloseError
+		if err := <-finished; !errors.As(err, &cerr) || cerr.Code != seatbelt.CloseGoingAway || cerr.Reason != "bye" {
+			t.Fatalf("expected CloseError with code 1001 but got %v", err)
+		}
+	})
+}
+
+func TestWebSocketRejectsNonUpgrade(t *testing.T) {
+	app := seatbelt.New()
+	app.WebSocket("/ws", func(c seatbelt.Context, conn *seatbelt.Conn) error {
+		return nil
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatalf("%+v executing request", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected HTTP 400 but got %d", resp.StatusCode)
+	}
+}
+
+func TestWebSocketAllowedOrigins(t *testing.T) {
+	app := seatbelt.New(seatbelt.Option{
+		WebSocketOrigins: []string{"https://admin.example.com"},
+	})
+	app.WebSocket("/ws", func(c seatbelt.Context, conn *seatbelt.Conn) error {
+		return conn.WriteMessage(seatbelt.TextMessage, []byte("welcome"))
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	status, client := dialWebSocket(t, srv, "/ws", http.Header{
+		"Origin": {"https://admin.example.com"},
+	})
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("expected HTTP 101 but got %d", status)
+	}
+	defer client.conn.Close()
+
+	if _, payload := client.read(t); string(payload) != "welcome" {
+		t.Fatalf("expected welcome message but got %q", payload)
+	}
+	if opcode, _ := client.read(t); opcode != 0x8 {
+		t.Fatalf("expected close frame once the handler returned but got opcode %d", opcode)
+	}
+}
+
+func TestWebSocketInvalidCloseCodes(t *testing.T) {
+	app := seatbelt.New()
+	app.WebSocket("/ws", func(c seatbelt.Context, conn *seatbelt.Conn) error {
+		_, _, err := conn.ReadMessage()
+		return err
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		payload []byte
+		code    uint16
+	}{
+		{"no status received", []byte{0x03, 0xed}, seatbelt.CloseProtocolError},
+		{"abnormal closure", []byte{0x03, 0xee}, seatbelt.CloseProtocolError},
+		{"tls handshake", []byte{0x03, 0xf7}, seatbelt.CloseProtocolError},
+		{"out of range", []byte{0x13, 0x88}, seatbelt.CloseProtocolError},
+		{"truncated", []byte{0x03}, seatbelt.CloseProtocolError},
+		{"application code", []byte{0x0f, 0xa0}, 4000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, client := dialWebSocket(t, srv, "/ws", http.Header{
+				"Origin": {srv.URL},
+			})
+			if status != http.StatusSwitchingProtocols {
+				t.Fatalf("expected HTTP 101 but got %d", status)
+			}
+			defer client.conn.Close()
+
+			client.write(t, 0x8, tt.payload)
+
+			opcode, payload := client.read(t)
+			if opcode != 0x8 || len(payload) < 2 || binary.BigEndian.Uint16(payload) != tt.code {
+				t.Fatalf("expected close frame with code %d but got opcode %d with %v", tt.code, opcode, payload)
+			}
+		})
+	}
+
+	t.Run("empty close frame", func(t *testing.T) {
+		status, client := dialWebSocket(t, srv, "/ws", http.Header{
+			"Origin": {srv.URL},
+		})
+		if status != http.StatusSwitchingProtocols {
+			t.Fatalf("expected HTTP 101 but got %d", status)
+		}
+		defer client.conn.Close()
+
+		client.write(t, 0x8, nil)
+
+		if opcode, payload := client.read(t); opcode != 0x8 || len(payload) != 0 {
+			t.Fatalf("expected empty close frame but got opcode %d with %v", opcode, payload)
+		}
+	})
+}
+
+func TestWebSocketErrorHandlerAfterHijack(t *testing.T) {
+	handled := make(chan [2]error, 1)
+
+	app := seatbelt.New()
+	app.SetErrorHandler(func(c seatbelt.Context, err error) {
+		serr := c.String(http.StatusInternalServerError, err.Error())
+		_, werr := c.Response().Write([]byte(err.Error()))
+		handled <- [2]error{serr, werr}
+	})
+	app.WebSocket("/ws", func(c seatbelt.Context, conn *seatbelt.Conn) error {
+		return errors.New("something went wrong")
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	status, client := dialWebSocket(t, srv, "/ws", http.Header{
+		"Origin": {srv.URL},
+	})
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("expected HTTP 101 but got %d", status)
+	}
+	defer client.conn.Close()
+
+	opcode, payload := client.read(t)
+	if opcode != 0x8 || binary.BigEndian.Uint16(payload) != seatbelt.CloseInternalError {
+		t.Fatalf("expected close frame with code 1011 but got opcode %d with %v", opcode, payload)
+	}
+
+	errs := <-handled
+	if !errors.Is(errs[0], seatbelt.ErrResponseCommitted) {
+		t.Fatalf("expected rendering from the error handler to fail with ErrResponseCommitted but got %v", errs[0])
+	}
+	if !errors.Is(errs[1], http.ErrHijacked) {
+		t.Fatalf("expected writing from the error handler to fail with http.ErrHijacked but got %v", errs[1])
+	}
+	if rest, _ := io.ReadAll(client.br); len(rest) != 0 {
+		t.Fatalf("expected nothing after the close frame but got %q", rest)
+	}
+}